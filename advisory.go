@@ -0,0 +1,110 @@
+package pglike
+
+import (
+	"context"
+	"sync"
+)
+
+// advisoryLocks holds the in-process state of pg_advisory_lock keys
+// (int64 -> *advisoryLock). Locks coordinate connections within one process
+// only; other processes using the same database file don't see them.
+var advisoryLocks sync.Map
+
+// advisoryLock is a session-level advisory lock. As in PostgreSQL, the owning
+// session may acquire it repeatedly and must release it as many times.
+type advisoryLock struct {
+	held  chan struct{} // holds a value while a session owns the lock
+	state sync.Mutex    // guards owner and depth
+	owner any
+	depth int
+}
+
+// advisoryKey combines the two-argument form pg_advisory_lock(int4, int4) into one key.
+func advisoryKey(hi, lo int64) int64 {
+	return int64(uint64(uint32(hi))<<32 | uint64(uint32(lo)))
+}
+
+func getAdvisoryLock(key int64) *advisoryLock {
+	if l, ok := advisoryLocks.Load(key); ok {
+		return l.(*advisoryLock)
+	}
+	l, _ := advisoryLocks.LoadOrStore(key, &advisoryLock{held: make(chan struct{}, 1)})
+	return l.(*advisoryLock)
+}
+
+// acquire takes the lock for session, waiting until it is free or ctx is done,
+// in which case it returns ctx.Err().
+func (l *advisoryLock) acquire(ctx context.Context, session any) error {
+	if l.reenter(session) {
+		return nil
+	}
+	select {
+	case l.held <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	l.own(session)
+	return nil
+}
+
+// tryAcquire takes the lock for session if it is free, reporting whether it did.
+func (l *advisoryLock) tryAcquire(session any) bool {
+	if l.reenter(session) {
+		return true
+	}
+	select {
+	case l.held <- struct{}{}:
+	default:
+		return false
+	}
+	l.own(session)
+	return true
+}
+
+// reenter takes the lock again if session already owns it.
+func (l *advisoryLock) reenter(session any) bool {
+	l.state.Lock()
+	defer l.state.Unlock()
+	if l.owner != session {
+		return false
+	}
+	l.depth++
+	return true
+}
+
+// own records session as the owner of a newly taken lock.
+func (l *advisoryLock) own(session any) {
+	l.state.Lock()
+	l.owner, l.depth = session, 1
+	l.state.Unlock()
+}
+
+// release gives up one hold of the lock, reporting false if session doesn't own it.
+func (l *advisoryLock) release(session any) bool {
+	l.state.Lock()
+	defer l.state.Unlock()
+	if l.owner != session {
+		return false
+	}
+	l.depth--
+	if l.depth == 0 {
+		l.owner = nil
+		<-l.held
+	}
+	return true
+}
+
+// releaseAdvisoryLocks drops every advisory lock held by session, as
+// pg_advisory_unlock_all() and the end of a PostgreSQL session do.
+func releaseAdvisoryLocks(session any) {
+	advisoryLocks.Range(func(_, v any) bool {
+		l := v.(*advisoryLock)
+		l.state.Lock()
+		if l.owner == session {
+			l.owner, l.depth = nil, 0
+			<-l.held
+		}
+		l.state.Unlock()
+		return true
+	})
+}
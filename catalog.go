@@ -0,0 +1,458 @@
+package pglike
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// catalog is what pglike knows about a database's schema beyond what SQLite
+// records: the PG type and default each column was declared with, the order of a table's
+// columns, the sequences that fill columns by default, the enum types it
+// creates and the queries behind its materialized views. A database keeps its
+// catalog in the _pglike_catalog table, so the catalog outlives connections
+// and is rolled back with the transaction that changed it. A catalog is never
+// modified once built; changes make a new one (see with).
+type catalog struct {
+	// columns maps "table.column" to the column's declared type as pg_typeof
+	// names it ("boolean", "numeric", "timestamp with time zone", an enum's
+	// name, ...), with "[]" appended for arrays.
+	columns map[string]string
+	// seqDefaults maps "table.column" to the sequence a column declared
+	// DEFAULT nextval('seq') takes its values from.
+	seqDefaults map[string]string
+	// addedDefaults maps "table.column" to the default expression of a column
+	// ALTER TABLE ... ADD COLUMN added with a default SQLite can't add.
+	addedDefaults map[string]string
+	// declaredDefaults maps "table.column" to the DEFAULT expression a column
+	// was declared with.
+	declaredDefaults map[string]string
+	// columnLists maps a table's name to the names of its columns in order,
+	// for INSERTs without a column list.
+	columnLists map[string][]string
+	// matViews maps a materialized view's lower-cased name to how it was
+	// created.
+	matViews map[string]matView
+	// enums maps an enum type's lower-cased name to its labels in order.
+	enums map[string][]string
+}
+
+// matView is a materialized view: the query that fills it, and whether it was
+// created as a table holding a snapshot of the query rather than as a view.
+type matView struct {
+	query    string
+	snapshot bool
+}
+
+// catalogTableDDL creates the table a database's catalog is kept in, one row
+// per entry: kind is the sort of entry, name identifies it within its kind
+// and value holds it.
+const catalogTableDDL = "CREATE TABLE IF NOT EXISTS _pglike_catalog (kind TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, PRIMARY KEY (kind, name))"
+
+// catalogColumn entries are named "table.column" and hold the column's
+// declared type.
+const catalogColumn = "column"
+
+// catalogSeqDefault entries are named "table.column" and hold the name of the
+// sequence the column's default draws from.
+const catalogSeqDefault = "sequence default"
+
+// catalogAddedDefault entries are named "table.column" and hold the default
+// expression, already in SQLite's dialect, that INSERTs get for the column.
+const catalogAddedDefault = "added default"
+
+// catalogDeclaredDefault entries are named "table.column" and hold the
+// expression the column was declared DEFAULT, for INSERTs that give DEFAULT
+// for the column in some rows but not others.
+const catalogDeclaredDefault = "default"
+
+// catalogColumnList entries are named by a table's name and hold the names of
+// its columns, in order, as a JSON array.
+const catalogColumnList = "column list"
+
+// catalogMatView and catalogMatViewSnapshot entries are named by a
+// materialized view's lower-cased name and hold its query; the view was created
+// as a plain view or as a snapshot table respectively.
+const (
+	catalogMatView         = "matview"
+	catalogMatViewSnapshot = "matview snapshot"
+)
+
+// catalogEnum entries are named by an enum type's lower-cased name and hold its
+// labels as a JSON array.
+const catalogEnum = "enum"
+
+// catalogChange is an entry a statement sets in the catalog, or removes from
+// it. When duplicate is set the entry must be new: if the catalog has it
+// already, the statement fails with duplicate as its message (SQLSTATE 42710).
+type catalogChange struct {
+	kind, name, value string
+	remove            bool
+	duplicate         string
+}
+
+func newCatalog() *catalog {
+	return &catalog{
+		columns:          make(map[string]string),
+		seqDefaults:      make(map[string]string),
+		addedDefaults:    make(map[string]string),
+		declaredDefaults: make(map[string]string),
+		columnLists:      make(map[string][]string),
+		matViews:         make(map[string]matView),
+		enums:            make(map[string][]string),
+	}
+}
+
+// with returns the catalog with changes applied.
+func (c *catalog) with(changes []catalogChange) *catalog {
+	next := &catalog{
+		columns:          maps.Clone(c.columns),
+		seqDefaults:      maps.Clone(c.seqDefaults),
+		addedDefaults:    maps.Clone(c.addedDefaults),
+		declaredDefaults: maps.Clone(c.declaredDefaults),
+		columnLists:      maps.Clone(c.columnLists),
+		matViews:         maps.Clone(c.matViews),
+		enums:            maps.Clone(c.enums),
+	}
+	for _, ch := range changes {
+		next.apply(ch)
+	}
+	return next
+}
+
+// apply makes a change to a catalog still being built.
+func (c *catalog) apply(ch catalogChange) {
+	switch ch.kind {
+	case catalogColumn, catalogSeqDefault, catalogAddedDefault, catalogDeclaredDefault:
+		m := c.columnEntries(ch.kind)
+		if ch.remove {
+			delete(m, ch.name)
+		} else {
+			m[ch.name] = ch.value
+		}
+	case catalogColumnList:
+		var names []string
+		if ch.remove || json.Unmarshal([]byte(ch.value), &names) != nil {
+			delete(c.columnLists, ch.name)
+		} else {
+			c.columnLists[ch.name] = names
+		}
+	case catalogMatView, catalogMatViewSnapshot:
+		if ch.remove {
+			delete(c.matViews, ch.name)
+		} else {
+			c.matViews[ch.name] = matView{query: ch.value, snapshot: ch.kind == catalogMatViewSnapshot}
+		}
+	case catalogEnum:
+		var labels []string
+		if ch.remove || json.Unmarshal([]byte(ch.value), &labels) != nil {
+			delete(c.enums, ch.name)
+		} else {
+			c.enums[ch.name] = labels
+		}
+	}
+}
+
+// columnEntryKinds are the kinds of catalog entries named "table.column".
+var columnEntryKinds = []string{catalogColumn, catalogSeqDefault, catalogAddedDefault, catalogDeclaredDefault}
+
+// columnEntries returns the catalog's entries of kind, one of
+// columnEntryKinds, keyed by "table.column".
+func (c *catalog) columnEntries(kind string) map[string]string {
+	switch kind {
+	case catalogSeqDefault:
+		return c.seqDefaults
+	case catalogAddedDefault:
+		return c.addedDefaults
+	case catalogDeclaredDefault:
+		return c.declaredDefaults
+	}
+	return c.columns
+}
+
+// tableColumns returns the names of the columns the catalog records for table.
+func (c *catalog) tableColumns(table string) []string {
+	return tableKeys(c.columns, table)
+}
+
+// tableKeys returns, sorted, the columns of table that m, keyed by
+// "table.column", has entries for.
+func tableKeys[V any](m map[string]V, table string) []string {
+	var cols []string
+	for key := range m {
+		if col, ok := strings.CutPrefix(key, table+"."); ok {
+			cols = append(cols, col)
+		}
+	}
+	slices.Sort(cols)
+	return cols
+}
+
+// scope is the catalog as one statement sees it. tables maps the names and
+// aliases the statement gives its tables to the tables, so that a column
+// reference is resolved to the table it belongs to; changes collects the
+// entries the statement sets in the catalog or removes from it.
+type scope struct {
+	cat     *catalog
+	tables  map[string]string
+	changes []catalogChange
+}
+
+func newScope(cat *catalog, tokens []Token) *scope {
+	return &scope{cat: cat, tables: statementTables(tokens)}
+}
+
+// columnType returns the declared type of column col. A qualified reference
+// (qualifier is one of the statement's table names or aliases) looks only at
+// that table. An unqualified one looks at the statement's tables that have
+// such a column, which must agree on its type.
+func (s *scope) columnType(qualifier, col string) (string, bool) {
+	if qualifier != "" {
+		table, ok := s.tables[qualifier]
+		if !ok {
+			table = qualifier
+		}
+		typ, ok := s.cat.columns[table+"."+col]
+		return typ, ok
+	}
+	found := ""
+	for _, table := range s.tables {
+		typ, ok := s.cat.columns[table+"."+col]
+		if !ok {
+			continue
+		}
+		if found != "" && found != typ {
+			return "", false
+		}
+		found = typ
+	}
+	return found, found != ""
+}
+
+// refType returns the declared type of the column ref names, when ref is just
+// a column reference: col or table.col.
+func (s *scope) refType(ref []Token) (string, bool) {
+	ref = trimTokenWhitespace(ref)
+	qualifier := ""
+	if len(ref) == 3 && ref[0].Kind == TokIdent && ref[1].Kind == TokDot {
+		qualifier, ref = identName(ref[0]), ref[2:]
+	}
+	if len(ref) != 1 || ref[0].Kind != TokIdent {
+		return "", false
+	}
+	return s.columnType(qualifier, identName(ref[0]))
+}
+
+// change records an entry the statement sets in the catalog, or removes.
+func (s *scope) change(ch catalogChange) {
+	s.changes = append(s.changes, ch)
+}
+
+// statementTables maps the tables a statement names after FROM, JOIN, UPDATE,
+// INTO, USING or TABLE, and the aliases it gives them, to the table names.
+func statementTables(tokens []Token) map[string]string {
+	tables := make(map[string]string)
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind != TokKeyword {
+			continue
+		}
+		switch t.Value {
+		case "FROM", "JOIN", "UPDATE", "INTO", "USING", "TABLE":
+		default:
+			continue
+		}
+		for {
+			j := skipTrivia(tokens, i+1)
+			for j < len(tokens) && tokens[j].Kind == TokKeyword &&
+				(tokens[j].Value == "IF" || tokens[j].Value == "NOT" || tokens[j].Value == "EXISTS" || tokens[j].Value == "ONLY") {
+				j = skipTrivia(tokens, j+1)
+			}
+			if j >= len(tokens) || tokens[j].Kind != TokIdent {
+				break
+			}
+			for j+2 < len(tokens) && tokens[j+1].Kind == TokDot && tokens[j+2].Kind == TokIdent {
+				j += 2 // schema.table
+			}
+			name := identName(tokens[j])
+			tables[name] = name
+			i = j
+			k := skipTrivia(tokens, j+1)
+			if k < len(tokens) && tokens[k].Kind == TokKeyword && tokens[k].Value == "AS" {
+				k = skipTrivia(tokens, k+1)
+			}
+			if k < len(tokens) && tokens[k].Kind == TokIdent {
+				tables[identName(tokens[k])] = name
+				i = k
+				k = skipTrivia(tokens, k+1)
+			}
+			// FROM a, b
+			if t.Value != "FROM" || k >= len(tokens) || tokens[k].Kind != TokComma {
+				break
+			}
+			i = k
+		}
+	}
+	return tables
+}
+
+// standaloneCatalog is the catalog Translate and TranslateMulti translate
+// against, as they have no database: the statements they translate change it
+// directly. It is process-wide. Connections translate against their database's
+// catalog instead.
+var standaloneCatalog = struct {
+	mu  sync.Mutex
+	cat *catalog
+}{cat: newCatalog()}
+
+// translateStandalone translates one or more statements against
+// standaloneCatalog, applying the changes they make to it.
+func translateStandalone(tokens []Token) string {
+	standaloneCatalog.mu.Lock()
+	cat := standaloneCatalog.cat
+	standaloneCatalog.mu.Unlock()
+
+	sc := newScope(cat, tokens)
+	sql := Reassemble(translateTokens(tokens, sc))
+	if len(sc.changes) > 0 {
+		standaloneCatalog.mu.Lock()
+		standaloneCatalog.cat = standaloneCatalog.cat.with(sc.changes)
+		standaloneCatalog.mu.Unlock()
+		// Cached translations may depend on the old catalog.
+		defaultCache.reset()
+	}
+	return sql
+}
+
+// sharedCatalog holds the committed catalog of a database for the connections
+// a Connector opens to it, so that they need not read it before every
+// statement.
+type sharedCatalog struct {
+	mu  sync.Mutex
+	cat *catalog
+}
+
+// catalog returns the catalog the connection translates against: its own
+// while it is in a transaction that has changed the catalog, the shared one
+// otherwise.
+func (c *conn) catalog() *catalog {
+	if c.txCatalog != nil {
+		return c.txCatalog
+	}
+	c.shared.mu.Lock()
+	defer c.shared.mu.Unlock()
+	return c.shared.cat
+}
+
+// translate translates one statement against the connection's catalog,
+// returning the SQLite statement and the catalog changes it makes.
+func (c *conn) translate(tokens []Token) (string, []catalogChange) {
+	sc := newScope(c.catalog(), tokens)
+	return Reassemble(translateTokens(tokens, sc)), sc.changes
+}
+
+// execWithCatalog runs exec, which executes a translated statement, and writes
+// the catalog changes the statement makes, under a savepoint so that both take
+// effect or neither does. Afterwards it reloads the catalog if the statement
+// changed it or may have ended a transaction that did.
+func (c *conn) execWithCatalog(ctx context.Context, changes []catalogChange, exec func() (driver.Result, error)) (driver.Result, error) {
+	if len(changes) == 0 {
+		r, err := exec()
+		if c.txCatalog != nil {
+			c.refreshCatalog()
+		}
+		return r, err
+	}
+	if err := c.execDirect(ctx, "SAVEPOINT _pglike_catalog"); err != nil {
+		return nil, wrapContextError(ctx, err)
+	}
+	r, err := exec()
+	if err == nil {
+		err = c.writeCatalog(ctx, changes)
+	}
+	if err == nil {
+		err = c.execDirect(ctx, "RELEASE _pglike_catalog")
+	}
+	if err != nil {
+		_ = c.execDirect(context.Background(), "ROLLBACK TO _pglike_catalog")
+		_ = c.execDirect(context.Background(), "RELEASE _pglike_catalog")
+		return nil, err
+	}
+	c.refreshCatalog()
+	return r, nil
+}
+
+// writeCatalog stores changes in the database's catalog.
+func (c *conn) writeCatalog(ctx context.Context, changes []catalogChange) error {
+	for _, ch := range changes {
+		var err error
+		switch {
+		case ch.remove:
+			_, err = c.execTranslated(ctx, "DELETE FROM _pglike_catalog WHERE kind = ? AND name = ?",
+				[]driver.NamedValue{{Ordinal: 1, Value: ch.kind}, {Ordinal: 2, Value: ch.name}}, false)
+		case ch.duplicate != "":
+			_, err = c.execTranslated(ctx, "INSERT INTO _pglike_catalog (kind, name, value) VALUES (?, ?, ?)",
+				[]driver.NamedValue{{Ordinal: 1, Value: ch.kind}, {Ordinal: 2, Value: ch.name}, {Ordinal: 3, Value: ch.value}}, false)
+			var pgErr *PGError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				err = &PGError{Code: "42710", Message: ch.duplicate, inner: err} // duplicate_object
+			}
+		default:
+			_, err = c.execTranslated(ctx, "INSERT OR REPLACE INTO _pglike_catalog (kind, name, value) VALUES (?, ?, ?)",
+				[]driver.NamedValue{{Ordinal: 1, Value: ch.kind}, {Ordinal: 2, Value: ch.name}, {Ordinal: 3, Value: ch.value}}, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshCatalog reads the catalog back from the database. Inside a
+// transaction the connection keeps it to itself until the transaction ends;
+// otherwise it becomes the shared catalog.
+func (c *conn) refreshCatalog() {
+	if c.raw != nil && !c.raw.GetAutocommit() {
+		c.txCatalog = c.loadCatalog()
+		return
+	}
+	// Read under the lock, so that a slower connection cannot replace the
+	// shared catalog with an older one.
+	c.shared.mu.Lock()
+	c.shared.cat = c.loadCatalog()
+	c.shared.mu.Unlock()
+	c.txCatalog = nil
+}
+
+// loadCatalog reads the database's catalog. A database without a
+// _pglike_catalog table, such as a read-only one, has an empty catalog.
+func (c *conn) loadCatalog() *catalog {
+	cat := newCatalog()
+	r, err := c.queryDirect(context.Background(), "SELECT kind, name, value FROM _pglike_catalog")
+	if err != nil {
+		return cat
+	}
+	defer r.Close()
+	_ = r.Columns() // ncruces requires Columns() before Next()
+	dest := make([]driver.Value, 3)
+	for r.Next(dest) == nil {
+		cat.apply(catalogChange{kind: catalogText(dest[0]), name: catalogText(dest[1]), value: catalogText(dest[2])})
+	}
+	return cat
+}
+
+// catalogText returns a _pglike_catalog value as a string.
+func catalogText(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
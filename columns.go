@@ -0,0 +1,370 @@
+package pglike
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// declaredTypeNames maps DDL type keywords to the names pg_typeof reports.
+// TIMESTAMP, TIME, CHARACTER and DOUBLE take further words and are handled by
+// declaredType.
+var declaredTypeNames = map[string]string{
+	"BOOLEAN": "boolean", "BOOL": "boolean",
+	"SMALLINT": "smallint", "INT2": "smallint", "SMALLSERIAL": "smallint",
+	"INTEGER": "integer", "INT": "integer", "INT4": "integer", "SERIAL": "integer",
+	"BIGINT": "bigint", "INT8": "bigint", "BIGSERIAL": "bigint",
+	"REAL": "real", "FLOAT4": "real", "FLOAT8": "double precision",
+	"NUMERIC": "numeric", "DECIMAL": "numeric",
+	"TEXT": "text", "VARCHAR": "character varying", "CHAR": "character", "CITEXT": "citext",
+	"TIMESTAMPTZ": "timestamp with time zone", "TIMETZ": "time with time zone",
+	"DATE": "date", "INTERVAL": "interval",
+	"UUID": "uuid", "BYTEA": "bytea", "JSON": "json", "JSONB": "jsonb",
+	"TSVECTOR": "tsvector", "TSQUERY": "tsquery",
+}
+
+// declaredType returns the pg_typeof name of the column type whose tokens begin
+// typ, or "" when it is not recognised.
+func declaredType(typ []Token, sc *scope) string {
+	var words []string
+	for _, t := range typ {
+		if t.Kind == TokWhitespace {
+			continue
+		}
+		if t.Kind != TokKeyword && t.Kind != TokIdent {
+			break
+		}
+		words = append(words, strings.ToUpper(t.Value))
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	withTZ := len(words) >= 4 && words[1] == "WITH" && words[2] == "TIME" && words[3] == "ZONE"
+	switch words[0] {
+	case "TIMESTAMP":
+		if withTZ {
+			return "timestamp with time zone"
+		}
+		return "timestamp without time zone"
+	case "TIME":
+		if withTZ {
+			return "time with time zone"
+		}
+		return "time without time zone"
+	case "CHARACTER":
+		if len(words) > 1 && words[1] == "VARYING" {
+			return "character varying"
+		}
+		return "character"
+	case "DOUBLE":
+		return "double precision"
+	}
+	if name, ok := declaredTypeNames[words[0]]; ok {
+		return name
+	}
+	if _, ok := sc.enum(words[0]); ok {
+		return strings.ToLower(words[0])
+	}
+	return ""
+}
+
+// isArrayTypeDecl reports whether the column type whose tokens begin typ is an
+// array type: INTEGER[], VARCHAR(10)[] or TEXT ARRAY.
+func isArrayTypeDecl(typ []Token) bool {
+	for i := 0; i < len(typ); i++ {
+		switch t := typ[i]; {
+		case t.Kind == TokBracket && t.Value == "[":
+			return true
+		case t.Kind == TokKeyword && t.Value == "ARRAY":
+			return true
+		case t.Kind == TokParen && t.Value == "(":
+			i = skipParenGroup(typ, i)
+		case t.Kind != TokWhitespace && t.Kind != TokKeyword && t.Kind != TokIdent:
+			return false
+		}
+	}
+	return false
+}
+
+// columnDeclType returns the catalog type of a column declared with the type
+// whose tokens begin typ: its pg_typeof name, with "[]" appended for an array,
+// or "" when the type is not recognised.
+func columnDeclType(typ []Token, sc *scope) string {
+	declared := declaredType(typ, sc)
+	if declared != "" && isArrayTypeDecl(typ) {
+		declared += "[]"
+	}
+	return declared
+}
+
+// recordColumnTypes records in the catalog the declared type of the columns
+// that CREATE TABLE and ALTER TABLE ... ADD COLUMN define with a recognised
+// type, for the rewrites that depend on it: enum ordering, numeric array_agg,
+// timestamp subtraction, pg_typeof and so on. It also records the columns'
+// DEFAULT expressions and the order of the table's columns, for INSERTs that
+// use DEFAULT or have no column list. It forgets the columns that
+// ALTER TABLE ... DROP COLUMN and DROP TABLE remove, and follows those that
+// ALTER TABLE ... RENAME renames.
+func recordColumnTypes(tokens []Token, sc *scope) {
+	for _, stmt := range splitStatements(tokens) {
+		i := skipTrivia(stmt, 0)
+		if i >= len(stmt) || stmt[i].Kind != TokKeyword {
+			continue
+		}
+		switch stmt[i].Value {
+		case "CREATE":
+			recordCreateTable(stmt, i, sc)
+		case "ALTER":
+			recordAlterTable(stmt, i, sc)
+		case "DROP":
+			recordDropTable(stmt, i, sc)
+		}
+	}
+}
+
+// recordCreateTable records the columns of CREATE [TEMP|TEMPORARY|UNLOGGED]
+// TABLE [IF NOT EXISTS] name (...) at stmt[i], replacing whatever the catalog
+// had for the table unless IF NOT EXISTS leaves an existing one alone.
+func recordCreateTable(stmt []Token, i int, sc *scope) {
+	j := i + 1
+	for j < len(stmt) && !(stmt[j].Kind == TokKeyword && stmt[j].Value == "TABLE") {
+		if stmt[j].Kind != TokWhitespace && stmt[j].Kind != TokKeyword {
+			return
+		}
+		j++
+	}
+	table, ifNotExists := "", false
+	for ; j < len(stmt) && !(stmt[j].Kind == TokParen && stmt[j].Value == "("); j++ {
+		switch {
+		case stmt[j].Kind == TokKeyword && stmt[j].Value == "AS":
+			return // CREATE TABLE ... AS SELECT
+		case stmt[j].Kind == TokKeyword && stmt[j].Value == "EXISTS":
+			ifNotExists = true
+		case stmt[j].Kind == TokIdent:
+			table = identName(stmt[j])
+		}
+	}
+	if j >= len(stmt) || table == "" {
+		return
+	}
+	existing := sc.cat.tableColumns(table)
+	if _, listed := sc.cat.columnLists[table]; ifNotExists && (len(existing) > 0 || listed) {
+		return
+	}
+	for _, col := range existing {
+		sc.change(catalogChange{kind: catalogColumn, name: table + "." + col, remove: true})
+	}
+	for _, kind := range []string{catalogAddedDefault, catalogDeclaredDefault} {
+		for _, col := range tableKeys(sc.cat.columnEntries(kind), table) {
+			sc.change(catalogChange{kind: kind, name: table + "." + col, remove: true})
+		}
+	}
+	defs, _ := parseFuncArgs(stmt, j)
+	var names []string
+	for _, def := range defs {
+		def = trimTokenWhitespace(def)
+		if len(def) == 0 || def[0].Kind != TokIdent && (def[0].Kind != TokKeyword || tableConstraintWords[def[0].Value]) {
+			continue
+		}
+		names = append(names, identName(def[0]))
+		recordColumnDef(table, def, sc)
+	}
+	setColumnList(table, names, sc)
+}
+
+// setColumnList records names as the columns of table, in order.
+func setColumnList(table string, names []string, sc *scope) {
+	b, _ := json.Marshal(names)
+	sc.change(catalogChange{kind: catalogColumnList, name: table, value: string(b)})
+}
+
+// recordColumnDef records the column that def, "name type ...", defines in
+// table: its type and its DEFAULT expression.
+func recordColumnDef(table string, def []Token, sc *scope) {
+	if len(def) < 3 || def[0].Kind != TokIdent || def[1].Kind != TokWhitespace {
+		return
+	}
+	name := table + "." + identName(def[0])
+	if typ := columnDeclType(def[2:], sc); typ != "" {
+		sc.change(catalogChange{kind: catalogColumn, name: name, value: typ})
+	}
+	if expr := columnDefault(def); len(expr) > 0 {
+		sc.change(catalogChange{kind: catalogDeclaredDefault, name: name, value: Reassemble(expr)})
+	}
+}
+
+// columnConstraintWords start the column constraints that can follow a
+// column's DEFAULT expression.
+var columnConstraintWords = map[string]bool{
+	"NOT": true, "NULL": true, "CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true,
+	"REFERENCES": true, "COLLATE": true, "GENERATED": true,
+}
+
+// columnDefault returns the expression of the DEFAULT clause of the column
+// definition def, or nil when it has none.
+func columnDefault(def []Token) []Token {
+	depth := 0
+	for k, t := range def {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "DEFAULT":
+			start := skipTrivia(def, k+1)
+			end := start
+			for d := 0; end < len(def); end++ {
+				switch u := def[end]; {
+				case u.Kind == TokParen && u.Value == "(":
+					d++
+				case u.Kind == TokParen && u.Value == ")":
+					d--
+				case d == 0 && end > start && u.Kind == TokKeyword && columnConstraintWords[u.Value]:
+					return trimTokenWhitespace(def[start:end])
+				}
+			}
+			return trimTokenWhitespace(def[start:end])
+		}
+	}
+	return nil
+}
+
+// recordAlterTable records the columns ALTER TABLE [IF EXISTS] [ONLY] name at
+// stmt[i] adds with ADD [COLUMN] [IF NOT EXISTS] and removes with DROP
+// [COLUMN] [IF EXISTS].
+func recordAlterTable(stmt []Token, i int, sc *scope) {
+	j, ok := peekKeyword(stmt, i+1, "TABLE")
+	if !ok {
+		return
+	}
+	j = skipTrivia(stmt, j+1)
+	for j < len(stmt) && stmt[j].Kind == TokKeyword && (stmt[j].Value == "IF" || stmt[j].Value == "EXISTS" || stmt[j].Value == "ONLY") {
+		j = skipTrivia(stmt, j+1)
+	}
+	if j >= len(stmt) || stmt[j].Kind != TokIdent {
+		return
+	}
+	table := identName(stmt[j])
+	names, listed := sc.cat.columnLists[table]
+	names = slices.Clone(names)
+	for _, action := range splitTopLevel(stmt[j+1:]) {
+		action = trimTokenWhitespace(action)
+		if len(action) > 0 && strings.EqualFold(action[0].Value, "RENAME") {
+			recordRename(table, action, sc)
+			continue
+		}
+		if len(action) == 0 || action[0].Kind != TokKeyword || (action[0].Value != "ADD" && action[0].Value != "DROP") {
+			continue
+		}
+		k := skipTrivia(action, 1)
+		if c, ok := peekKeyword(action, k, "COLUMN"); ok {
+			k = skipTrivia(action, c+1)
+		}
+		ifClause := false
+		for k < len(action) && action[k].Kind == TokKeyword && (action[k].Value == "IF" || action[k].Value == "NOT" || action[k].Value == "EXISTS") {
+			ifClause = true
+			k = skipTrivia(action, k+1)
+		}
+		if k >= len(action) || action[k].Kind != TokIdent {
+			continue
+		}
+		col := identName(action[k])
+		name := table + "." + col
+		if action[0].Value == "DROP" {
+			for _, kind := range columnEntryKinds {
+				if _, ok := sc.cat.columnEntries(kind)[name]; ok {
+					sc.change(catalogChange{kind: kind, name: name, remove: true})
+				}
+			}
+			names = slices.DeleteFunc(names, func(n string) bool { return n == col })
+			continue
+		}
+		if _, exists := sc.cat.columns[name]; exists && ifClause {
+			continue
+		}
+		if !slices.Contains(names, col) {
+			names = append(names, col)
+		}
+		recordColumnDef(table, action[k:], sc)
+	}
+	if listed && !slices.Equal(names, sc.cat.columnLists[table]) {
+		setColumnList(table, names, sc)
+	}
+}
+
+// recordRename moves the catalog entries of what the ALTER TABLE action
+// RENAME [COLUMN] old TO new, or RENAME TO new, renames in table. Entries the
+// catalog still has under the new name are dropped.
+func recordRename(table string, action []Token, sc *scope) {
+	var names []string
+	for _, t := range action[1:] {
+		if t.Kind == TokIdent || t.Kind == TokKeyword && t.Value != "COLUMN" && t.Value != "TO" {
+			names = append(names, identName(t))
+		}
+	}
+	switch len(names) {
+	case 1: // RENAME TO new
+		to := names[0]
+		var cols []string
+		for _, kind := range columnEntryKinds {
+			for _, col := range tableKeys(sc.cat.columnEntries(kind), to) {
+				sc.change(catalogChange{kind: kind, name: to + "." + col, remove: true})
+			}
+			cols = append(cols, tableKeys(sc.cat.columnEntries(kind), table)...)
+		}
+		slices.Sort(cols)
+		for _, col := range slices.Compact(cols) {
+			moveColumnEntries(table+"."+col, to+"."+col, sc)
+		}
+		if _, ok := sc.cat.columnLists[to]; ok {
+			sc.change(catalogChange{kind: catalogColumnList, name: to, remove: true})
+		}
+		if list, ok := sc.cat.columnLists[table]; ok {
+			sc.change(catalogChange{kind: catalogColumnList, name: table, remove: true})
+			setColumnList(to, list, sc)
+		}
+	case 2: // RENAME [COLUMN] old TO new
+		moveColumnEntries(table+"."+names[0], table+"."+names[1], sc)
+		if list, ok := sc.cat.columnLists[table]; ok {
+			list = slices.Clone(list)
+			if n := slices.Index(list, names[0]); n >= 0 {
+				list[n] = names[1]
+				setColumnList(table, list, sc)
+			}
+		}
+	}
+}
+
+// moveColumnEntries moves the catalog entries for column from, "table.column",
+// to column to.
+func moveColumnEntries(from, to string, sc *scope) {
+	for _, kind := range columnEntryKinds {
+		if value, ok := sc.cat.columnEntries(kind)[from]; ok {
+			sc.change(catalogChange{kind: kind, name: from, remove: true})
+			sc.change(catalogChange{kind: kind, name: to, value: value})
+		}
+	}
+}
+
+// recordDropTable forgets the columns, their defaults and their order, of the
+// tables DROP TABLE [IF EXISTS] name [, ...] at stmt[i] drops.
+func recordDropTable(stmt []Token, i int, sc *scope) {
+	j, ok := peekKeyword(stmt, i+1, "TABLE")
+	if !ok {
+		return
+	}
+	for _, t := range stmt[j+1:] {
+		if t.Kind != TokIdent {
+			continue
+		}
+		table := identName(t)
+		for _, kind := range columnEntryKinds {
+			for _, col := range tableKeys(sc.cat.columnEntries(kind), table) {
+				sc.change(catalogChange{kind: kind, name: table + "." + col, remove: true})
+			}
+		}
+		if _, ok := sc.cat.columnLists[table]; ok {
+			sc.change(catalogChange{kind: catalogColumnList, name: table, remove: true})
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package pglike
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CopyTo emulates COPY ... TO STDOUT as a convenience API (the COPY wire protocol
+// is not emulated). It runs query, or SELECT * FROM query when query is a bare
+// table name, and writes every row to w in PostgreSQL's COPY format:
+//
+//   - "text": tab-separated, NULL as \N, with backslash escapes, matching the
+//     data sections written by pg_dump
+//   - "csv": comma-separated, NULL as an empty unquoted field, with fields quoted
+//     when they contain a delimiter, quote or line break (empty strings are
+//     quoted to tell them apart from NULL)
+func CopyTo(db *sql.DB, query string, w io.Writer, format string) error {
+	csv := false
+	switch strings.ToLower(format) {
+	case "text", "":
+	case "csv":
+		csv = true
+	default:
+		return fmt.Errorf("COPY format %q not recognized", format)
+	}
+	if isTableName(query) {
+		query = "SELECT * FROM " + query
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	vals := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			if i > 0 {
+				if csv {
+					bw.WriteByte(',')
+				} else {
+					bw.WriteByte('\t')
+				}
+			}
+			switch {
+			case v == nil && csv:
+			case v == nil:
+				bw.WriteString(`\N`)
+			case csv:
+				bw.WriteString(copyCSVField(copyValue(v)))
+			default:
+				bw.WriteString(copyTextField(copyValue(v)))
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// isTableName reports whether s is a bare, optionally schema-qualified, table name.
+func isTableName(s string) bool {
+	tokens := trimTokenWhitespace(Tokenize(s))
+	if len(tokens) == 3 && tokens[0].Kind == TokIdent && tokens[1].Kind == TokDot {
+		tokens = tokens[2:]
+	}
+	return len(tokens) == 1 && tokens[0].Kind == TokIdent
+}
+
+// copyValue renders a scanned value the way PostgreSQL's output functions do.
+func copyValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// copyTextEscaper escapes the characters COPY's text format treats specially.
+var copyTextEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// copyTextField escapes a value for COPY's text format.
+func copyTextField(s string) string {
+	return copyTextEscaper.Replace(s)
+}
+
+// copyCSVField quotes a value for COPY's CSV format when needed.
+func copyCSVField(s string) string {
+	if s != "" && !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// Copier is implemented by the driver's connections. CopyFrom stands in for
+// COPY table (columns) FROM STDIN: it inserts rows into table with multi-row
+// INSERTs inside a savepoint, so either every row is loaded or none is, and
+// returns the number of rows inserted. With no columns, each row gives a value
+// for every column of the table in order. Reach it through sql.Conn.Raw:
+//
+//	err := c.Raw(func(dc any) error {
+//		_, err := dc.(pglike.Copier).CopyFrom("people", []string{"id", "name"}, rows)
+//		return err
+//	})
+type Copier interface {
+	CopyFrom(table string, columns []string, rows [][]any) (int64, error)
+}
+
+var _ Copier = (*conn)(nil)
+
+// copyMaxParams caps the parameters bound per INSERT, below SQLite's default
+// limit of 32766.
+const copyMaxParams = 30000
+
+// CopyFrom implements Copier.
+func (c *conn) CopyFrom(table string, columns []string, rows [][]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	width := len(columns)
+	if width == 0 {
+		width = len(rows[0])
+	}
+	if width == 0 {
+		return 0, fmt.Errorf("pglike: CopyFrom: rows have no values")
+	}
+	for i, row := range rows {
+		if len(row) != width {
+			return 0, fmt.Errorf("pglike: CopyFrom: row %d has %d values, want %d", i+1, len(row), width)
+		}
+	}
+
+	head := "INSERT INTO " + quoteIdent(table)
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quoteIdent(col)
+		}
+		head += " (" + strings.Join(quoted, ", ") + ")"
+	}
+	head += " VALUES "
+	// Columns whose defaults SQLite can't apply itself, such as DEFAULT
+	// nextval('seq'), are filled in by translating the INSERT, which also
+	// converts values for INTERVAL columns to seconds.
+	cat := c.catalog()
+	translate := len(tableInsertDefaults(cat, table)) > 0 || len(intervalColumns(cat, table)) > 0
+
+	if err := c.execDirect(context.Background(), "SAVEPOINT _pglike_copy"); err != nil {
+		return 0, wrapError(err)
+	}
+	n, err := c.copyRows(head, width, translate, rows)
+	if err != nil {
+		_ = c.execDirect(context.Background(), "ROLLBACK TO _pglike_copy")
+		_ = c.execDirect(context.Background(), "RELEASE _pglike_copy")
+		return 0, err
+	}
+	if err := c.execDirect(context.Background(), "RELEASE _pglike_copy"); err != nil {
+		return 0, wrapError(err)
+	}
+	return n, nil
+}
+
+// copyRows inserts rows in batches of as many rows as copyMaxParams allows,
+// preparing the statement for a full batch once, translated if translate is
+// set.
+func (c *conn) copyRows(head string, width int, translate bool, rows [][]any) (int64, error) {
+	perBatch := max(copyMaxParams/width, 1)
+	placeholders := "(?" + strings.Repeat(", ?", width-1) + ")"
+	stmts := make(map[int]driver.Stmt)
+	defer func() {
+		for _, s := range stmts {
+			s.Close()
+		}
+	}()
+
+	var n int64
+	for len(rows) > 0 {
+		batch := rows[:min(perBatch, len(rows))]
+		rows = rows[len(batch):]
+		s, ok := stmts[len(batch)]
+		if !ok {
+			var err error
+			query := head + placeholders + strings.Repeat(", "+placeholders, len(batch)-1)
+			if translate {
+				query, _ = c.translate(Tokenize(query))
+			}
+			s, err = c.inner.Prepare(query)
+			if err != nil {
+				return 0, wrapError(err)
+			}
+			stmts[len(batch)] = s
+		}
+		args := make([]driver.NamedValue, 0, len(batch)*width)
+		for _, row := range batch {
+			for _, v := range row {
+				dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+				if err != nil {
+					return 0, fmt.Errorf("pglike: CopyFrom: row %d: %w", n+1, err)
+				}
+				args = append(args, driver.NamedValue{Ordinal: len(args) + 1, Value: dv})
+			}
+		}
+		var r driver.Result
+		var err error
+		if execer, ok := s.(driver.StmtExecContext); ok {
+			r, err = execer.ExecContext(context.Background(), args)
+		} else {
+			r, err = s.Exec(namedToValues(args)) //nolint:staticcheck
+		}
+		if err != nil {
+			return 0, wrapError(err)
+		}
+		affected, err := r.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		n += affected
+	}
+	return n, nil
+}
+
+// errCopyFromStdin is returned for COPY ... FROM STDIN, whose data PostgreSQL
+// reads through the COPY protocol rather than from the statement.
+var errCopyFromStdin = &PGError{
+	Code:    "0A000", // feature_not_supported
+	Message: "COPY ... FROM STDIN is not supported; load the rows with CopyFrom (see pglike.Copier)",
+}
+
+// isCopyFromStdin reports whether any statement in query is COPY ... FROM STDIN.
+func isCopyFromStdin(query string) bool {
+	// Most queries never mention STDIN; skip tokenizing those.
+	found := false
+	for i := 0; i+5 <= len(query) && !found; i++ {
+		found = strings.EqualFold(query[i:i+5], "stdin")
+	}
+	if !found {
+		return false
+	}
+	for _, stmt := range splitStatements(Tokenize(query)) {
+		i := skipTrivia(stmt, 0)
+		if i >= len(stmt) || !strings.EqualFold(stmt[i].Value, "COPY") {
+			continue
+		}
+		for j := i + 1; j < len(stmt); j++ {
+			if stmt[j].Kind == TokKeyword && stmt[j].Value == "FROM" {
+				if k := skipTrivia(stmt, j+1); k < len(stmt) && strings.EqualFold(stmt[k].Value, "STDIN") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
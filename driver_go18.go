@@ -33,76 +33,92 @@ func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 		if err != nil {
 			return nil, err
 		}
-		return &tx{inner: t}, nil
+		return c.newTx(t), nil
 	}
 	return c.Begin()
 }
 
 // PrepareContext implements driver.ConnPrepareContext.
 func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	translated, err := Translate(query)
-	if err != nil {
-		return nil, err
+	if err := ctx.Err(); err != nil {
+		return nil, wrapContextError(ctx, err)
+	}
+	if isCopyFromStdin(query) {
+		return nil, errCopyFromStdin
+	}
+	tokens := Tokenize(query)
+	if err := checkStrict(tokens); err != nil {
+		return nil, withQuery(err, query, "")
 	}
-	translated, err = c.resolveSequenceCalls(translated)
+	translated, changes := c.translate(tokens)
+	resolved, err := c.resolveSequenceCalls(ctx, translated)
 	if err != nil {
-		return nil, err
+		return nil, withQuery(err, query, translated)
 	}
 	if preparer, ok := c.inner.(driver.ConnPrepareContext); ok {
-		s, err := preparer.PrepareContext(ctx, translated)
+		s, err := preparer.PrepareContext(ctx, resolved)
 		if err != nil {
-			return nil, wrapError(err)
+			return nil, withQuery(wrapContextError(ctx, err), query, resolved)
 		}
-		return &stmt{inner: s}, nil
+		return c.newStmt(s, query, translated, resolved, changes), nil
 	}
-	s, err := c.inner.Prepare(translated)
+	s, err := c.inner.Prepare(resolved)
 	if err != nil {
-		return nil, wrapError(err)
+		return nil, withQuery(wrapContextError(ctx, err), query, resolved)
 	}
-	return &stmt{inner: s}, nil
+	return c.newStmt(s, query, translated, resolved, changes), nil
 }
 
 // ExecContext implements driver.ExecerContext.
 // It supports multiple semicolon-separated statements in a single call,
 // matching PostgreSQL's behavior. Each statement is translated and executed
-// individually. The result from the last statement is returned.
+// individually, so it is translated against the catalog as the statements
+// before it left it. The result from the last statement is returned.
 func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	stmts, err := TranslateMulti(query)
-	if err != nil {
-		return nil, err
+	if err := ctx.Err(); err != nil {
+		return nil, wrapContextError(ctx, err)
 	}
-
-	// Single statement — use fast path.
-	if len(stmts) == 1 {
-		resolved, err := c.resolveSequenceCalls(stmts[0].SQL)
-		if err != nil {
-			return nil, err
+	if isCopyFromStdin(query) {
+		return nil, errCopyFromStdin
+	}
+	stmts := splitStatements(Tokenize(query))
+	for _, stmtTokens := range stmts {
+		if err := checkStrict(stmtTokens); err != nil {
+			return nil, withQuery(err, query, "")
 		}
-		return c.execTranslated(ctx, resolved, args, isAlterAddColumnIfNotExists(query))
 	}
 
-	// Multi-statement — execute each individually, splitting args by param count.
+	// Execute each statement individually, splitting args by param count.
 	argOffset := 0
 	var lastResult driver.Result = driver.ResultNoRows
-	for _, ts := range stmts {
-		resolved, err := c.resolveSequenceCalls(ts.SQL)
-		if err != nil {
-			return nil, err
+	for _, stmtTokens := range stmts {
+		if err := ctx.Err(); err != nil {
+			return nil, wrapContextError(ctx, err)
 		}
-
-		var stmtArgs []driver.NamedValue
-		if ts.NumParams > 0 {
-			if argOffset+ts.NumParams > len(args) {
-				return nil, fmt.Errorf("pglike: multi-statement exec: need %d args for statement, have %d remaining",
-					ts.NumParams, len(args)-argOffset)
+		stmtArgs := args
+		if len(stmts) > 1 {
+			stmtArgs = nil
+			if n := countTokenParams(stmtTokens); n > 0 {
+				if argOffset+n > len(args) {
+					return nil, fmt.Errorf("pglike: multi-statement exec: need %d args for statement, have %d remaining",
+						n, len(args)-argOffset)
+				}
+				stmtArgs = renumberArgs(args[argOffset : argOffset+n])
+				argOffset += n
 			}
-			stmtArgs = renumberArgs(args[argOffset : argOffset+ts.NumParams])
-			argOffset += ts.NumParams
 		}
+		suppressDupCol := isAlterAddColumnIfNotExists(Reassemble(stmtTokens))
 
-		r, err := c.execTranslated(ctx, resolved, stmtArgs, isAlterAddColumnIfNotExists(resolved))
+		translated, changes := c.translate(stmtTokens)
+		resolved, err := c.resolveSequenceCalls(ctx, translated)
 		if err != nil {
-			return nil, err
+			return nil, withQuery(err, query, translated)
+		}
+		r, err := c.execWithCatalog(ctx, changes, func() (driver.Result, error) {
+			return c.execTranslated(ctx, resolved, stmtArgs, suppressDupCol)
+		})
+		if err != nil {
+			return nil, withQuery(err, query, resolved)
 		}
 		lastResult = r
 	}
@@ -121,7 +137,7 @@ func (c *conn) execTranslated(ctx context.Context, translated string, args []dri
 			if suppressDupCol && isDuplicateColumnError(err) {
 				return driver.ResultNoRows, nil
 			}
-			return nil, wrapError(err)
+			return nil, wrapContextError(ctx, err)
 		}
 		// ErrSkip: fall through to prepare+exec
 	}
@@ -138,7 +154,7 @@ func (c *conn) execTranslated(ctx context.Context, translated string, args []dri
 		if suppressDupCol && isDuplicateColumnError(err) {
 			return driver.ResultNoRows, nil
 		}
-		return nil, wrapError(err)
+		return nil, wrapContextError(ctx, err)
 	}
 	defer s.Close()
 
@@ -148,7 +164,7 @@ func (c *conn) execTranslated(ctx context.Context, translated string, args []dri
 			if suppressDupCol && isDuplicateColumnError(err) {
 				return driver.ResultNoRows, nil
 			}
-			return nil, wrapError(err)
+			return nil, wrapContextError(ctx, err)
 		}
 		return &result{inner: r}, nil
 	}
@@ -157,11 +173,24 @@ func (c *conn) execTranslated(ctx context.Context, translated string, args []dri
 		if suppressDupCol && isDuplicateColumnError(err) {
 			return driver.ResultNoRows, nil
 		}
-		return nil, wrapError(err)
+		return nil, wrapContextError(ctx, err)
 	}
 	return &result{inner: r}, nil
 }
 
+// wrapContextError wraps err like wrapError, except that once ctx is done the
+// error is reported as PG's query_canceled (SQLSTATE 57014) and unwraps to
+// ctx.Err(), so errors.Is(err, context.DeadlineExceeded) holds.
+func wrapContextError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return &PGError{Code: "57014", Message: "canceling statement due to statement timeout", inner: ctx.Err()}
+	case context.Canceled:
+		return &PGError{Code: "57014", Message: "canceling statement due to user request", inner: ctx.Err()}
+	}
+	return wrapError(err)
+}
+
 // renumberArgs creates a copy of args with ordinals renumbered starting from 1.
 func renumberArgs(args []driver.NamedValue) []driver.NamedValue {
 	out := make([]driver.NamedValue, len(args))
@@ -173,28 +202,41 @@ func renumberArgs(args []driver.NamedValue) []driver.NamedValue {
 
 // ExecContext implements driver.StmtExecContext.
 func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	if execer, ok := s.inner.(driver.StmtExecContext); ok {
-		r, err := execer.ExecContext(ctx, args)
-		if err != nil {
-			return nil, wrapError(err)
-		}
-		return &result{inner: r}, nil
+	if err := s.resolveSequences(ctx); err != nil {
+		return nil, err
 	}
-	values := namedToValues(args)
-	return s.Exec(values) //nolint:staticcheck
+	return s.conn.execWithCatalog(ctx, s.changes, func() (driver.Result, error) {
+		if execer, ok := s.inner.(driver.StmtExecContext); ok {
+			r, err := execer.ExecContext(ctx, args)
+			if err != nil {
+				return nil, withQuery(wrapContextError(ctx, err), s.query, s.translated)
+			}
+			return &result{inner: r}, nil
+		}
+		return s.execResolved(namedToValues(args))
+	})
 }
 
 // QueryContext implements driver.StmtQueryContext.
 func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if len(s.changes) > 0 {
+		// DDL run with Query: the catalog changes are written as for Exec.
+		if _, err := s.ExecContext(ctx, args); err != nil {
+			return nil, err
+		}
+		return noRows{}, nil
+	}
+	if err := s.resolveSequences(ctx); err != nil {
+		return nil, err
+	}
 	if queryer, ok := s.inner.(driver.StmtQueryContext); ok {
 		r, err := queryer.QueryContext(ctx, args)
 		if err != nil {
-			return nil, wrapError(err)
+			return nil, withQuery(wrapContextError(ctx, err), s.query, s.translated)
 		}
-		return &rows{inner: r}, nil
+		return &rows{inner: r, ctx: ctx, opts: s.opts, stmt: s}, nil
 	}
-	values := namedToValues(args)
-	return s.Query(values) //nolint:staticcheck
+	return s.queryResolved(namedToValues(args))
 }
 
 // namedToValues converts NamedValue args to positional Value args.
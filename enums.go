@@ -0,0 +1,315 @@
+package pglike
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// enumRegistry records the enum types declared with RegisterEnum and their
+// values in declaration order. Type names are case-insensitive, like unquoted
+// PG identifiers.
+var enumRegistry = struct {
+	mu    sync.RWMutex
+	types map[string][]string
+}{types: make(map[string][]string)}
+
+// RegisterEnum declares a PostgreSQL enum type so that casts such as
+// 'active'::status are validated against values. Registering an existing name
+// replaces its values. The registry is process-wide and shared by all
+// connections. Types created with CREATE TYPE are kept in the database's catalog
+// instead, and take precedence over registered ones of the same name.
+func RegisterEnum(name string, values []string) {
+	enumRegistry.mu.Lock()
+	enumRegistry.types[strings.ToLower(name)] = slices.Clone(values)
+	enumRegistry.mu.Unlock()
+	// Cached translations may predate the type.
+	defaultCache.reset()
+}
+
+// lookupEnum returns the values of a registered enum type.
+func lookupEnum(name string) ([]string, bool) {
+	enumRegistry.mu.RLock()
+	defer enumRegistry.mu.RUnlock()
+	values, ok := enumRegistry.types[strings.ToLower(name)]
+	return values, ok
+}
+
+// enum returns the labels of enum type name: one created in the database, or
+// else one declared with RegisterEnum.
+func (s *scope) enum(name string) ([]string, bool) {
+	if values, ok := s.cat.enums[strings.ToLower(name)]; ok {
+		return values, true
+	}
+	return lookupEnum(name)
+}
+
+// enumJSON returns an enum type's labels as a JSON array.
+func enumJSON(values []string) string {
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// castEnum validates value against values, the labels of the enum type name,
+// returning the error PostgreSQL reports (SQLSTATE 22P02) for values outside
+// the type. Nil values means there is no such type.
+func castEnum(value, name string, values []string) (string, error) {
+	if values == nil {
+		return "", fmt.Errorf("type %q does not exist", name)
+	}
+	if !slices.Contains(values, value) {
+		return "", fmt.Errorf("invalid input value for enum %s: %q", name, value)
+	}
+	return value, nil
+}
+
+// translateEnumDDL handles the enum type statements as changes to the catalog,
+// replacing them with no-ops since SQLite has no types:
+//
+//	CREATE TYPE name AS ENUM ('v1', 'v2', ...)   SQLSTATE 42710 if name exists
+//	ALTER TYPE name ADD VALUE [IF NOT EXISTS] 'v' [BEFORE | AFTER 'w']
+//	DROP TYPE [IF EXISTS] name [, ...] [CASCADE | RESTRICT]
+//	  SQLSTATE 2BP01 if columns use the type; CASCADE drops them instead
+//
+// The changes take effect with the statement, so a rolled-back CREATE TYPE
+// leaves no type behind. ALTER and DROP also apply to types declared with
+// RegisterEnum: ALTER copies the type into the catalog, and DROP removes it from
+// the process-wide registry at once. Columns later declared with the type get a CHECK constraint (see
+// enumColumnType); constraints already created keep the values they were
+// created with. TYPE, ENUM and VALUE are matched as identifiers, since type is
+// a common column name. Other forms of these statements are left alone.
+func translateEnumDDL(tokens []Token, sc *scope) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword {
+		return tokens
+	}
+	j, ok := enumWord(tokens, i+1, "TYPE")
+	if !ok {
+		return tokens
+	}
+	switch tokens[i].Value {
+	case "CREATE":
+		return translateCreateEnum(tokens, j+1, sc)
+	case "ALTER":
+		return translateAlterEnum(tokens, j+1, sc)
+	case "DROP":
+		return translateDropEnum(tokens, j+1, sc)
+	}
+	return tokens
+}
+
+// enumWord returns the index of the next significant token from start if it is
+// the keyword or identifier w.
+func enumWord(tokens []Token, start int, w string) (int, bool) {
+	j := skipTrivia(tokens, start)
+	if j < len(tokens) && (tokens[j].Kind == TokKeyword || tokens[j].Kind == TokIdent) && strings.EqualFold(tokens[j].Value, w) {
+		return j, true
+	}
+	return start, false
+}
+
+// enumTypeName reads a possibly schema-qualified type name from start,
+// returning the name without its schema and the index after it.
+func enumTypeName(tokens []Token, start int) (string, int) {
+	name := ""
+	k := skipTrivia(tokens, start)
+	for k < len(tokens) && (tokens[k].Kind == TokIdent || tokens[k].Kind == TokDot) {
+		if tokens[k].Kind == TokIdent {
+			name = identName(tokens[k])
+		}
+		k++
+	}
+	return name, k
+}
+
+// enumLabel returns the value of the string literal at tokens[i].
+func enumLabel(tokens []Token, i int) (string, bool) {
+	if i >= len(tokens) || tokens[i].Kind != TokString || !strings.HasPrefix(tokens[i].Value, "'") {
+		return "", false
+	}
+	return strings.ReplaceAll(strings.Trim(tokens[i].Value, "'"), "''", "'"), true
+}
+
+// missingEnumType returns a statement failing as PG does for an unknown type.
+func missingEnumType(name string) []Token {
+	return Tokenize("SELECT pg_enum_cast('', '" + strings.ReplaceAll(name, "'", "''") + "', NULL)")
+}
+
+func translateCreateEnum(tokens []Token, start int, sc *scope) []Token {
+	name, k := enumTypeName(tokens, start)
+	as, ok := peekKeyword(tokens, k, "AS")
+	if name == "" || !ok {
+		return tokens
+	}
+	e, ok := enumWord(tokens, as+1, "ENUM")
+	if !ok {
+		return tokens
+	}
+	open := skipTrivia(tokens, e+1)
+	if open >= len(tokens) || tokens[open].Kind != TokParen || tokens[open].Value != "(" {
+		return tokens
+	}
+	args, _ := parseFuncArgs(tokens, open)
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		v, ok := enumLabel(arg, 0)
+		if !ok || len(arg) != 1 {
+			return tokens
+		}
+		values = append(values, v)
+	}
+	sc.change(catalogChange{kind: catalogEnum, name: strings.ToLower(name), value: enumJSON(values),
+		duplicate: fmt.Sprintf("type %q already exists", name)})
+	return Tokenize("SELECT NULL WHERE 0")
+}
+
+func translateAlterEnum(tokens []Token, start int, sc *scope) []Token {
+	name, k := enumTypeName(tokens, start)
+	add, ok := peekKeyword(tokens, k, "ADD")
+	if name == "" || !ok {
+		return tokens
+	}
+	k, ok = enumWord(tokens, add+1, "VALUE")
+	if !ok {
+		return tokens
+	}
+	if j, ok := peekKeyword(tokens, k+1, "IF"); ok {
+		if j, ok = peekKeyword(tokens, j+1, "NOT"); ok {
+			if j, ok = peekKeyword(tokens, j+1, "EXISTS"); ok {
+				k = j
+			}
+		}
+	}
+	k = skipTrivia(tokens, k+1)
+	value, ok := enumLabel(tokens, k)
+	if !ok {
+		return tokens
+	}
+	// BEFORE 'w' / AFTER 'w' places the value next to an existing one.
+	neighbor, after := "", true
+	if j, ok := enumWord(tokens, k+1, "BEFORE"); ok {
+		neighbor, after = "", false
+		if neighbor, ok = enumLabel(tokens, skipTrivia(tokens, j+1)); !ok {
+			return tokens
+		}
+	} else if j, ok := enumWord(tokens, k+1, "AFTER"); ok {
+		if neighbor, ok = enumLabel(tokens, skipTrivia(tokens, j+1)); !ok {
+			return tokens
+		}
+	}
+
+	values, ok := sc.enum(name)
+	if !ok {
+		return missingEnumType(name)
+	}
+	if !slices.Contains(values, value) {
+		at := len(values)
+		if neighbor != "" {
+			if at = slices.Index(values, neighbor); at < 0 {
+				return tokens
+			}
+			if after {
+				at++
+			}
+		}
+		values = slices.Insert(slices.Clone(values), at, value)
+		sc.change(catalogChange{kind: catalogEnum, name: strings.ToLower(name), value: enumJSON(values)})
+	}
+	return Tokenize("SELECT NULL WHERE 0")
+}
+
+func translateDropEnum(tokens []Token, start int, sc *scope) []Token {
+	ifExists := false
+	if j, ok := peekKeyword(tokens, start, "IF"); ok {
+		if j, ok = peekKeyword(tokens, j+1, "EXISTS"); ok {
+			ifExists, start = true, j+1
+		}
+	}
+	var names []string
+	k := start
+	for {
+		var name string
+		name, k = enumTypeName(tokens, start)
+		if name == "" {
+			return tokens
+		}
+		names = append(names, name)
+		k = skipTrivia(tokens, k)
+		if k >= len(tokens) || tokens[k].Kind != TokComma {
+			break
+		}
+		start = k + 1
+	}
+	_, cascade := enumWord(tokens, k, "CASCADE")
+
+	// Columns of the types are dropped with CASCADE, and otherwise keep the
+	// types from being dropped.
+	var drops []string
+	for _, name := range names {
+		if _, ok := sc.enum(name); !ok && !ifExists {
+			return missingEnumType(name)
+		}
+		for _, col := range enumColumns(sc.cat, name) {
+			if !cascade {
+				return Tokenize("SELECT pg_type_in_use('" + strings.ReplaceAll(name, "'", "''") + "')")
+			}
+			table, column, _ := strings.Cut(col, ".")
+			drops = append(drops, "ALTER TABLE "+quoteIdent(table)+" DROP COLUMN "+quoteIdent(column))
+		}
+	}
+	registered := false
+	enumRegistry.mu.Lock()
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := sc.cat.enums[key]; ok {
+			sc.change(catalogChange{kind: catalogEnum, name: key, remove: true})
+		}
+		if _, ok := enumRegistry.types[key]; ok {
+			delete(enumRegistry.types, key)
+			registered = true
+		}
+	}
+	enumRegistry.mu.Unlock()
+	if registered {
+		defaultCache.reset()
+	}
+	if len(drops) > 0 {
+		return Tokenize(strings.Join(drops, "; "))
+	}
+	return Tokenize("SELECT NULL WHERE 0")
+}
+
+// enumColumns returns the "table.column" names of the columns the catalog
+// records as declared with enum type name or an array of it.
+func enumColumns(cat *catalog, name string) []string {
+	key := strings.ToLower(name)
+	var cols []string
+	for col, typ := range cat.columns {
+		if typ == key || typ == key+"[]" {
+			cols = append(cols, col)
+		}
+	}
+	slices.Sort(cols)
+	return cols
+}
+
+// enumColumnType returns the type for a column declared in CREATE or ALTER
+// TABLE with the enum type t, where out ends with the column name:
+// TEXT with a CHECK constraint limiting the column to the enum's values.
+func enumColumnType(out []Token, t Token, sc *scope) ([]Token, bool) {
+	values, ok := sc.enum(identName(t))
+	if !ok {
+		return nil, false
+	}
+	j := prevSignificant(out, len(out))
+	if j < 0 || (out[j].Kind != TokIdent && out[j].Kind != TokKeyword) || !isColumnNamePosition(out, j) {
+		return nil, false
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return Tokenize("TEXT CHECK (" + out[j].Raw + " IN (" + strings.Join(quoted, ", ") + "))"), true
+}
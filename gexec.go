@@ -0,0 +1,114 @@
+package pglike
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExecGenerated emulates psql's \gexec as a scripting aid: it runs genQuery,
+// which must return SQL text, and then executes every non-NULL field of every
+// returned row, in order, as a statement of its own. Each generated statement goes
+// through the normal translation pipeline, so it may use PostgreSQL syntax and
+// may itself contain several statements.
+//
+// All generated statements are read before any is executed. Execution stops at
+// the first error.
+func ExecGenerated(db *sql.DB, genQuery string) error {
+	rows, err := db.Query(genQuery)
+	if err != nil {
+		return err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+
+	var stmts []string
+	for rows.Next() {
+		fields := make([]sql.NullString, len(cols))
+		dest := make([]any, len(cols))
+		for i := range fields {
+			dest[i] = &fields[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		for _, f := range fields {
+			if f.Valid {
+				stmts = append(stmts, f.String)
+			}
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			return fmt.Errorf("generated statement %d (%s): %w", i+1, s, err)
+		}
+	}
+	return nil
+}
+
+// ExecScript runs a multi-statement SQL script, such as a schema dump, in a
+// single transaction. The script is split on semicolons outside string
+// literals, dollar quotes and comments, and each statement goes through the
+// normal translation pipeline. BEGIN, START TRANSACTION, COMMIT and END
+// statements in the script are skipped, since the whole script already runs
+// in one transaction.
+//
+// At the first failing statement the transaction is rolled back and ExecScript
+// returns a PGError whose message starts with the 1-based statement number and
+// which keeps the statement's SQLSTATE and text; the original error is
+// available with errors.Unwrap. The rollback includes what the script recorded
+// in the database's catalog, such as enum types and declared column types.
+func ExecScript(db *sql.DB, script string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for i, stmt := range splitStatements(Tokenize(script)) {
+		if isTxControl(stmt) {
+			continue
+		}
+		if _, err := tx.Exec(strings.TrimSpace(Reassemble(stmt))); err != nil {
+			tx.Rollback()
+			var pgErr *PGError
+			if !errors.As(err, &pgErr) {
+				pgErr = &PGError{Code: "XX000", Message: err.Error()} // internal_error
+			}
+			return &PGError{
+				Code:            pgErr.Code,
+				Message:         fmt.Sprintf("statement %d: %s", i+1, pgErr.Message),
+				Query:           pgErr.Query,
+				TranslatedQuery: pgErr.TranslatedQuery,
+				inner:           err,
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// isTxControl reports whether stmt begins or commits a transaction.
+func isTxControl(stmt []Token) bool {
+	i := skipTrivia(stmt, 0)
+	if i >= len(stmt) {
+		return false
+	}
+	switch strings.ToUpper(stmt[i].Value) {
+	case "BEGIN", "COMMIT", "END":
+		return true
+	case "START":
+		j := skipTrivia(stmt, i+1)
+		return j < len(stmt) && strings.EqualFold(stmt[j].Value, "TRANSACTION")
+	}
+	return false
+}
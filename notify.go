@@ -0,0 +1,131 @@
+package pglike
+
+import "sync"
+
+// Notification is a message sent with NOTIFY or pg_notify.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// notifyHub holds the in-process LISTEN subscriptions. As with advisory locks,
+// only connections within one process see each other's notifications.
+var notifyHub = struct {
+	mu        sync.Mutex
+	listeners map[string]map[*listener]bool
+}{listeners: make(map[string]map[*listener]bool)}
+
+// notifyPending holds the notifications sent inside an open transaction, keyed
+// by the session (*sqlite3.Conn) that sent them. As in PostgreSQL they are
+// delivered when the transaction commits and dropped if it rolls back.
+var notifyPending sync.Map
+
+// listener queues notifications without bound so that a slow receiver never
+// blocks the connection sending them.
+type listener struct {
+	mu     sync.Mutex
+	queue  []Notification
+	wake   chan struct{}
+	done   chan struct{}
+	closed sync.Once
+}
+
+// Listen subscribes to notifications on channel from every pglike connection in
+// this process, whether sent by NOTIFY channel, 'payload' or by
+// pg_notify('channel', 'payload'). Channel names are matched exactly; NOTIFY
+// folds an unquoted name to lower case, as PostgreSQL does.
+//
+// Notifications arrive in the order they were sent, on commit for those sent in
+// a transaction. Call the returned function to unsubscribe, which closes the
+// channel. The LISTEN and UNLISTEN statements are accepted but do nothing.
+func Listen(channel string) (<-chan Notification, func()) {
+	l := &listener{wake: make(chan struct{}, 1), done: make(chan struct{})}
+	out := make(chan Notification)
+
+	notifyHub.mu.Lock()
+	if notifyHub.listeners[channel] == nil {
+		notifyHub.listeners[channel] = make(map[*listener]bool)
+	}
+	notifyHub.listeners[channel][l] = true
+	notifyHub.mu.Unlock()
+
+	go l.forward(out)
+	unlisten := func() {
+		l.closed.Do(func() {
+			notifyHub.mu.Lock()
+			delete(notifyHub.listeners[channel], l)
+			if len(notifyHub.listeners[channel]) == 0 {
+				delete(notifyHub.listeners, channel)
+			}
+			notifyHub.mu.Unlock()
+			close(l.done)
+		})
+	}
+	return out, unlisten
+}
+
+// forward moves queued notifications to out until the listener is closed.
+func (l *listener) forward(out chan<- Notification) {
+	defer close(out)
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.mu.Unlock()
+			select {
+			case <-l.wake:
+				continue
+			case <-l.done:
+				return
+			}
+		}
+		n := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+		select {
+		case out <- n:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// notify queues n for the listeners of its channel, or holds it until commit
+// when session is inside a transaction.
+func notify(session any, n Notification) {
+	if pending, ok := notifyPending.Load(session); ok {
+		p := pending.(*[]Notification)
+		*p = append(*p, n)
+		return
+	}
+	deliverNotifications([]Notification{n})
+}
+
+func deliverNotifications(ns []Notification) {
+	notifyHub.mu.Lock()
+	defer notifyHub.mu.Unlock()
+	for _, n := range ns {
+		for l := range notifyHub.listeners[n.Channel] {
+			l.mu.Lock()
+			l.queue = append(l.queue, n)
+			l.mu.Unlock()
+			select {
+			case l.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// beginNotifications starts holding session's notifications for its transaction.
+func beginNotifications(session any) {
+	notifyPending.Store(session, new([]Notification))
+}
+
+// endNotifications ends session's transaction, delivering the notifications it
+// sent if it committed.
+func endNotifications(session any, commit bool) {
+	pending, ok := notifyPending.LoadAndDelete(session)
+	if ok && commit {
+		deliverNotifications(*pending.(*[]Notification))
+	}
+}
@@ -1,16 +1,37 @@
 package pglike
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
 // PGError represents a PostgreSQL-compatible error with an error code.
 type PGError struct {
-	Code    string // 5-char SQLSTATE code (e.g. "23505")
-	Message string // human-readable error message
-	inner   error  // underlying SQLite error
+	Code            string // 5-char SQLSTATE code (e.g. "23505")
+	Message         string // human-readable error message
+	Query           string // SQL text as passed to the driver, if known
+	TranslatedQuery string // SQLite statement that failed, if translation got that far
+	inner           error  // underlying SQLite error
 }
 
+// maxErrorQueryLen is how much of Query Error() shows.
+const maxErrorQueryLen = 200
+
+// Error returns the message, followed by the query on one line, cut to
+// maxErrorQueryLen bytes; the Query field keeps the full text.
 func (e *PGError) Error() string {
-	return e.Message
+	if e.Query == "" {
+		return e.Message
+	}
+	q := strings.Join(strings.Fields(e.Query), " ")
+	if len(q) > maxErrorQueryLen {
+		cut := maxErrorQueryLen
+		for cut > 0 && !utf8.RuneStart(q[cut]) {
+			cut--
+		}
+		q = q[:cut] + "..."
+	}
+	return e.Message + " (query: " + q + ")"
 }
 
 func (e *PGError) Unwrap() error {
@@ -22,6 +43,18 @@ func (e *PGError) SQLState() string {
 	return e.Code
 }
 
+// withQuery returns a copy of err with the query text recorded, if err is a
+// PGError that does not have it yet; other errors are returned unchanged.
+func withQuery(err error, query, translated string) error {
+	pgErr, ok := err.(*PGError)
+	if !ok || pgErr.Query != "" {
+		return err
+	}
+	e := *pgErr
+	e.Query, e.TranslatedQuery = query, translated
+	return &e
+}
+
 // wrapError wraps a SQLite error with a PG-compatible error code.
 // Returns the original error if it's nil or can't be classified.
 func wrapError(err error) error {
@@ -31,6 +64,10 @@ func wrapError(err error) error {
 
 	msg := err.Error()
 	code := classifySQLiteError(msg)
+	if strings.Contains(msg, "Cannot add a NOT NULL column with default value NULL") {
+		// PG names the column; SQLite's message doesn't, so say what went wrong.
+		msg = "column contains null values: a NOT NULL column added to a non-empty table needs a DEFAULT"
+	}
 
 	return &PGError{
 		Code:    code,
@@ -46,7 +83,8 @@ func classifySQLiteError(msg string) string {
 	switch {
 	case strings.Contains(lower, "unique constraint") || strings.Contains(lower, "unique_constraint"):
 		return "23505" // unique_violation
-	case strings.Contains(lower, "not null constraint") || strings.Contains(lower, "not_null_constraint"):
+	case strings.Contains(lower, "not null constraint") || strings.Contains(lower, "not_null_constraint") ||
+		strings.Contains(lower, "cannot add a not null column"):
 		return "23502" // not_null_violation
 	case strings.Contains(lower, "foreign key constraint") || strings.Contains(lower, "foreign_key_constraint"):
 		return "23503" // foreign_key_violation
@@ -56,6 +94,48 @@ func classifySQLiteError(msg string) string {
 		return "42P01" // undefined_table
 	case strings.Contains(lower, "no such column") || strings.Contains(lower, "no_such_column"):
 		return "42703" // undefined_column
+	case strings.Contains(lower, "is out of range for type") || strings.Contains(lower, "numeric field overflow") ||
+		strings.Contains(lower, "out of allowed range") || strings.Contains(lower, "input is out of range") ||
+		strings.Contains(lower, "bigint out of range"):
+		return "22003" // numeric_value_out_of_range
+	case strings.Contains(lower, "invalid input syntax for type interval"):
+		return "22007" // invalid_datetime_format
+	case strings.Contains(lower, "invalid input syntax") || strings.Contains(lower, "invalid input value"),
+		strings.Contains(lower, "malformed array literal") || strings.Contains(lower, "is not an integer"):
+		return "22P02" // invalid_text_representation
+	case strings.Contains(lower, "format()") || strings.Contains(lower, "format specifies argument"):
+		return "22023" // invalid_parameter_value
+	case strings.Contains(lower, "null values cannot be formatted"):
+		return "22004" // null_value_not_allowed
+	case strings.Contains(lower, "invalid regular expression option") || strings.Contains(lower, "step size cannot equal zero"),
+		strings.Contains(lower, "cannot replace existing key") || strings.Contains(lower, "cannot set path in scalar"):
+		return "22023" // invalid_parameter_value
+	case strings.Contains(lower, "invalid regular expression"):
+		return "2201B" // invalid_regular_expression
+	case strings.Contains(lower, "invalid escape string") || strings.Contains(lower, "must not end with escape character"):
+		return "22025" // invalid_escape_sequence
+	case strings.Contains(lower, "cannot take logarithm"):
+		return "2201E" // invalid_argument_for_logarithm
+	case strings.Contains(lower, "cannot take square root"):
+		return "2201F" // invalid_argument_for_power_function
+	case strings.Contains(lower, "division by zero"):
+		return "22012" // division_by_zero
+	case strings.Contains(lower, "negative substring length"):
+		return "22011" // substring_error
+	case strings.Contains(lower, "type \"") && strings.Contains(lower, "does not exist"):
+		return "42704" // undefined_object
+	case strings.Contains(lower, "because other objects depend on it"):
+		return "2BP01" // dependent_objects_still_exist
+	case strings.Contains(lower, "is not a materialized view"):
+		return "42809" // wrong_object_type
+	case strings.Contains(lower, "requested length too large"):
+		return "54000" // program_limit_exceeded
+	case strings.Contains(lower, "is not yet defined in this session"):
+		return "55000" // object_not_in_prerequisite_state
+	case strings.Contains(lower, "unrecognized configuration parameter"):
+		return "42704" // undefined_object
+	case strings.Contains(lower, "no such savepoint"):
+		return "3B001" // invalid_savepoint_specification
 	case strings.Contains(lower, "syntax error"):
 		return "42601" // syntax_error
 	default:
@@ -1,13 +1,31 @@
 package pglike
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"maps"
+	"math"
+	"math/big"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ncruces/go-sqlite3"
 )
@@ -26,6 +44,218 @@ func registerPGFunctions(conn *sqlite3.Conn) error {
 		return err
 	}
 
+	// uuid_generate_v4() -> same generator as gen_random_uuid (uuid-ossp name)
+	err = conn.CreateFunction("uuid_generate_v4", 0, sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultText(generateUUIDv4())
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_uuid(text) -> canonical lowercase UUID; target of x::uuid casts
+	err = conn.CreateFunction("pg_uuid", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			u, err := normalizeUUID(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultText(u)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_enum_cast(value, type, labels) -> value if it is one of the enum type's
+	// labels, a JSON array, or NULL if there is no such type; target of
+	// value::enum_type casts
+	err = conn.CreateFunction("pg_enum_cast", 3, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			var labels []string
+			if arg[2].Type() != sqlite3.NULL {
+				if err := json.Unmarshal([]byte(arg[2].Text()), &labels); err != nil {
+					ctx.ResultError(err)
+					return
+				}
+			}
+			v, err := castEnum(arg[0].Text(), arg[1].Text(), labels)
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultText(v)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_not_matview(name) -> always fails: name is not a materialized view;
+	// target of REFRESH and DROP MATERIALIZED VIEW for relations the catalog
+	// does not record as materialized views
+	err = conn.CreateFunction("pg_not_matview", 1, sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultError(fmt.Errorf("%q is not a materialized view", arg[0].Text()))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_type_in_use(type) -> always fails: columns still use the type; target
+	// of DROP TYPE without CASCADE
+	err = conn.CreateFunction("pg_type_in_use", 1, sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultError(fmt.Errorf("cannot drop type %s because other objects depend on it", arg[0].Text()))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_float8(x) -> x as a float; target of x::float8 casts. Unlike CAST(x AS REAL)
+	// it understands 'NaN' and '[-]Infinity' and rejects malformed text.
+	// SQLite cannot store NaN, so a NaN result comes back as NULL.
+	err = conn.CreateFunction("pg_float8", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			switch arg[0].Type() {
+			case sqlite3.NULL:
+				ctx.ResultNull()
+			case sqlite3.INTEGER, sqlite3.FLOAT:
+				ctx.ResultFloat(arg[0].Float())
+			default:
+				s := strings.TrimSpace(arg[0].Text())
+				f, err := strconv.ParseFloat(s, 64)
+				if errors.Is(err, strconv.ErrRange) {
+					ctx.ResultError(fmt.Errorf("%q is out of range for type double precision", s))
+					return
+				}
+				if err != nil {
+					ctx.ResultError(fmt.Errorf("invalid input syntax for type double precision: %q", arg[0].Text()))
+					return
+				}
+				ctx.ResultFloat(f)
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_interval(x) -> the length of interval x in seconds, e.g. 93600 for
+	// '1 day 02:00:00'; target of x::interval casts. Numbers are taken to be
+	// seconds already, so interval values pass through unchanged.
+	err = conn.CreateFunction("pg_interval", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			switch arg[0].Type() {
+			case sqlite3.NULL:
+				ctx.ResultNull()
+			case sqlite3.INTEGER, sqlite3.FLOAT:
+				ctx.ResultValue(arg[0])
+			default:
+				iv, err := parseInterval(arg[0].Text())
+				if err != nil {
+					ctx.ResultError(err)
+					return
+				}
+				resultSeconds(ctx, iv.Seconds())
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_timestamp_diff(a, b) -> a - b as an interval in seconds, e.g. 93600
+	// for '1 day 02:00:00'; target of timestamp subtraction.
+	err = conn.CreateFunction("pg_timestamp_diff", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			a, err := parseTimestampArg(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			b, err := parseTimestampArg(arg[1].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			resultSeconds(ctx, float64(a.Sub(b).Microseconds())/1e6)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_epoch(x) -> seconds since 1970-01-01 for a timestamp, or the length
+	// of an interval in seconds; target of EXTRACT(EPOCH FROM x). Text that is
+	// neither gives NULL, as unixepoch() does.
+	err = conn.CreateFunction("pg_epoch", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			switch arg[0].Type() {
+			case sqlite3.NULL:
+				ctx.ResultNull()
+			case sqlite3.INTEGER, sqlite3.FLOAT:
+				ctx.ResultFloat(arg[0].Float())
+			default:
+				if t, err := parseTimestampArg(arg[0].Text()); err == nil {
+					ctx.ResultFloat(float64(t.UnixMicro()) / 1e6)
+				} else if iv, err := parseInterval(arg[0].Text()); err == nil {
+					ctx.ResultFloat(iv.Seconds())
+				} else {
+					ctx.ResultNull()
+				}
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_numeric(x, p, s) -> x rounded to s decimal places; target of
+	// x::numeric(p,s) and CAST(x AS numeric(p,s)). Like PG it rounds half away
+	// from zero and rejects values with more than p-s integer digits. The
+	// result is an INTEGER for scale 0 and a REAL otherwise, so it compares
+	// and sorts as a number; the trailing zeros PG pads to scale s are lost.
+	err = conn.CreateFunction("pg_numeric", 3, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			s, err := castNumeric(arg[0].Text(), arg[1].Int(), arg[2].Int())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				ctx.ResultInt64(n)
+			} else if f, err := strconv.ParseFloat(s, 64); err == nil && s != "NaN" {
+				ctx.ResultFloat(f)
+			} else {
+				ctx.ResultText(s)
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	// md5(string) -> hex MD5 hash
 	err = conn.CreateFunction("md5", 1, sqlite3.DETERMINISTIC,
 		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
@@ -50,6 +280,78 @@ func registerPGFunctions(conn *sqlite3.Conn) error {
 		return err
 	}
 
+	// pg_digest(data, algo) -> raw hash bytes (pgcrypto digest). Unlike md5(),
+	// which returns hex text, the result is a BLOB; wrap it in encode(..., 'hex').
+	err = conn.CreateFunction("pg_digest", 2, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			var h hash.Hash
+			switch algo := strings.ToLower(arg[1].Text()); algo {
+			case "md5":
+				h = md5.New()
+			case "sha1":
+				h = sha1.New()
+			case "sha224":
+				h = sha256.New224()
+			case "sha256":
+				h = sha256.New()
+			case "sha384":
+				h = sha512.New384()
+			case "sha512":
+				h = sha512.New()
+			default:
+				ctx.ResultError(fmt.Errorf("cannot use %q, no such hash algorithm", algo))
+				return
+			}
+			h.Write(arg[0].RawBlob())
+			ctx.ResultBlob(h.Sum(nil))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_encode(bytes, format) -> text in 'hex', 'base64' or 'escape' format
+	err = conn.CreateFunction("pg_encode", 2, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			out, err := encodeBytes(arg[0].RawBlob(), arg[1].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultText(out)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_decode(text, format) -> bytes; inverse of pg_encode
+	err = conn.CreateFunction("pg_decode", 2, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			out, err := decodeBytes(arg[0].Text(), arg[1].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultBlob(out)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	// split_part(string, delimiter, field) -> nth field (1-indexed)
 	err = conn.CreateFunction("split_part", 3, sqlite3.DETERMINISTIC,
 		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
@@ -102,15 +404,144 @@ func registerPGFunctions(conn *sqlite3.Conn) error {
 		return err
 	}
 
+	// pg_div(a, b) -> a / b with PG's semantics: truncating integer division
+	// for integer operands, real division otherwise, and an error for a zero
+	// divisor; target of the / operator
+	err = conn.CreateFunction("pg_div", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			a, aInt := numericArg(arg[0])
+			b, bInt := numericArg(arg[1])
+			switch {
+			case b == 0:
+				ctx.ResultError(errors.New("division by zero"))
+			case aInt && bInt:
+				x, y := int64(a), int64(b)
+				if arg[0].Type() == sqlite3.INTEGER {
+					x = arg[0].Int64()
+				}
+				if arg[1].Type() == sqlite3.INTEGER {
+					y = arg[1].Int64()
+				}
+				if x == math.MinInt64 && y == -1 {
+					ctx.ResultError(errors.New("bigint out of range"))
+					return
+				}
+				ctx.ResultInt64(x / y)
+			default:
+				ctx.ResultFloat(a / b)
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_mod(a, b) -> a % b with PG's semantics: the remainder of truncating
+	// division, integer for integer operands and fractional otherwise, and an
+	// error for a zero divisor; target of the % operator and mod()
+	err = conn.CreateFunction("pg_mod", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			a, aInt := numericArg(arg[0])
+			b, bInt := numericArg(arg[1])
+			switch {
+			case b == 0:
+				ctx.ResultError(errors.New("division by zero"))
+			case aInt && bInt:
+				if arg[1].Int64() == -1 {
+					ctx.ResultInt64(0) // avoids overflowing MinInt64 % -1
+					return
+				}
+				ctx.ResultInt64(arg[0].Int64() % arg[1].Int64())
+			default:
+				ctx.ResultFloat(math.Mod(a, b))
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_series_step(step) -> step, raising an error when it is zero; guards
+	// the step of generate_series, which PG refuses to repeat forever
+	err = conn.CreateFunction("pg_series_step", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			if v, _ := numericArg(arg[0]); v == 0 {
+				ctx.ResultError(errors.New("step size cannot equal zero"))
+				return
+			}
+			ctx.ResultValue(arg[0])
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// COLLATE pg_citext compares lower(a) with lower(b), as PG's citext does;
+	// collation of CITEXT columns
+	err = conn.CreateCollation("pg_citext", func(a, b []byte) int {
+		return strings.Compare(strings.ToLower(string(a)), strings.ToLower(string(b)))
+	})
+	if err != nil {
+		return err
+	}
+
+	// pg_regexp_matches(string, pattern [, flags]) -> JSON array of the capture
+	// groups of the first match (see regexpMatches); target of regexp_matches
+	for _, nArg := range []int{2, 3} {
+		err = conn.CreateFunction("pg_regexp_matches", nArg, sqlite3.DETERMINISTIC,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				for _, a := range arg {
+					if a.Type() == sqlite3.NULL {
+						ctx.ResultNull()
+						return
+					}
+				}
+				flags := ""
+				if len(arg) == 3 {
+					flags = arg[2].Text()
+				}
+				groups, ok, err := regexpMatches(arg[0].Text(), arg[1].Text(), flags)
+				switch {
+				case err != nil:
+					ctx.ResultError(err)
+				case !ok:
+					ctx.ResultNull()
+				default:
+					ctx.ResultText(groups)
+				}
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	// pg_to_char(datetime_text, pg_format) -> formatted string
+	// pg_to_char(number, numeric_format) -> formatted number (see formatPGNumber)
 	err = conn.CreateFunction("pg_to_char", 2, sqlite3.DETERMINISTIC,
 		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
 			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
 				ctx.ResultNull()
 				return
 			}
-			dtStr := arg[0].Text()
 			pgFmt := arg[1].Text()
+			if isPGNumericFormat(pgFmt) {
+				ctx.ResultText(formatPGNumber(arg[0].Float(), pgFmt))
+				return
+			}
+			dtStr := arg[0].Text()
 			t, err := parseDateTime(dtStr)
 			if err != nil {
 				ctx.ResultText(dtStr)
@@ -123,19 +554,32 @@ func registerPGFunctions(conn *sqlite3.Conn) error {
 		return err
 	}
 
-	// pg_similar_match(str, pattern) -> 1 if matches SQL SIMILAR TO pattern, 0 otherwise
-	err = conn.CreateFunction("pg_similar_match", 2, sqlite3.DETERMINISTIC,
+	// pg_similar_match(str, pattern[, escape]) -> 1 if str matches the SQL
+	// SIMILAR TO pattern, 0 otherwise. The escape character defaults to '\'.
+	err = conn.CreateFunction("pg_similar_match", -1, sqlite3.DETERMINISTIC,
 		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
-			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
-				ctx.ResultInt64(0)
+			if len(arg) != 2 && len(arg) != 3 {
+				ctx.ResultError(fmt.Errorf("pg_similar_match() takes 2 or 3 arguments"))
 				return
 			}
-			str := arg[0].Text()
-			pattern := arg[1].Text()
-			re := convertSimilarToRegex(pattern)
-			matched, err := regexp.MatchString(re, str)
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultInt64(0)
+					return
+				}
+			}
+			escape := `\`
+			if len(arg) == 3 {
+				escape = arg[2].Text()
+			}
+			re, err := convertSimilarToRegex(arg[1].Text(), escape)
 			if err != nil {
-				ctx.ResultInt64(0)
+				ctx.ResultError(err)
+				return
+			}
+			matched, err := regexp.MatchString(re, arg[0].Text())
+			if err != nil {
+				ctx.ResultError(fmt.Errorf("invalid regular expression: %v", err))
 				return
 			}
 			if matched {
@@ -149,32 +593,978 @@ func registerPGFunctions(conn *sqlite3.Conn) error {
 		return err
 	}
 
-	// pg_typeof(expr) -> type name as string
-	// INNOCUOUS allows use in CHECK constraints and generated columns.
-	err = conn.CreateFunction("pg_typeof", 1, sqlite3.INNOCUOUS,
+	// pg_ts_match(document, query) -> document @@ query, with the document
+	// taken as the lexemes of a tsvector as pg_to_tsvector writes it, or else
+	// as the lower-cased words of the text, and the query as terms joined by & (and),
+	// | (or) and ! (not), with parentheses. A term ending in :* matches any word
+	// it starts. There is no stemming or stop-word removal.
+	err = conn.CreateFunction("pg_ts_match", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
 		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
-			switch arg[0].Type() {
-			case sqlite3.NULL:
-				ctx.ResultText("unknown")
-			case sqlite3.INTEGER:
-				ctx.ResultText("integer")
-			case sqlite3.FLOAT:
-				ctx.ResultText("double precision")
-			case sqlite3.TEXT:
-				ctx.ResultText("text")
-			case sqlite3.BLOB:
-				ctx.ResultText("bytea")
-			default:
-				ctx.ResultText("unknown")
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			matched, err := tsMatch(arg[0].Text(), arg[1].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
 			}
+			ctx.ResultBool(matched)
 		},
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	// pg_to_tsvector(text) -> the words of text in PG's tsvector form, e.g.
+	// 'cat':3 'fat':2 'the':1; target of to_tsvector
+	err = conn.CreateFunction("pg_to_tsvector", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			ctx.ResultText(tsVector(arg[0].Text()))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_plainto_tsquery(text) -> the words of text joined by &, without any
+	// operators it contains; target of plainto_tsquery
+	err = conn.CreateFunction("pg_plainto_tsquery", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			ctx.ResultText(strings.Join(tsWords(arg[0].Text()), " & "))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_ilike(str, pattern[, escape]) -> str ILIKE pattern. SQLite's LIKE
+	// only folds ASCII case; this folds Unicode case, so 'café' ILIKE 'CAFÉ'.
+	// The escape character defaults to '\'.
+	err = conn.CreateFunction("pg_ilike", -1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if len(arg) != 2 && len(arg) != 3 {
+				ctx.ResultError(fmt.Errorf("pg_ilike() takes 2 or 3 arguments"))
+				return
+			}
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+			}
+			escape := `\`
+			if len(arg) == 3 {
+				escape = arg[2].Text()
+			}
+			matched, err := likeFoldMatch(arg[0].Text(), arg[1].Text(), escape)
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultBool(matched)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_typeof(expr) -> type name as string
+	// INNOCUOUS allows use in CHECK constraints and generated columns.
+	err = conn.CreateFunction("pg_typeof", 1, sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			switch arg[0].Type() {
+			case sqlite3.NULL:
+				ctx.ResultText("unknown")
+			case sqlite3.INTEGER:
+				ctx.ResultText("integer")
+			case sqlite3.FLOAT:
+				ctx.ResultText("double precision")
+			case sqlite3.TEXT:
+				ctx.ResultText("text")
+			case sqlite3.BLOB:
+				ctx.ResultText("bytea")
+			default:
+				ctx.ResultText("unknown")
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_reverse(string) -> string with its characters (not bytes) reversed
+	err = conn.CreateFunction("pg_reverse", 1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			runes := []rune(arg[0].Text())
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			ctx.ResultText(string(runes))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_substr(string, from [, count]) -> substring with PG's bounds: positions
+	// before the first character are clamped rather than counted from the end as
+	// SQLite's substr does, so substring('hello', -1, 3) is 'h'. Bytea values are
+	// sliced by bytes.
+	for _, nArg := range []int{2, 3} {
+		err = conn.CreateFunction("pg_substr", nArg, sqlite3.DETERMINISTIC,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				for _, a := range arg {
+					if a.Type() == sqlite3.NULL {
+						ctx.ResultNull()
+						return
+					}
+				}
+				count := int64(-1)
+				if len(arg) == 3 {
+					if count = arg[2].Int64(); count < 0 {
+						ctx.ResultError(errors.New("negative substring length not allowed"))
+						return
+					}
+				}
+				if arg[0].Type() == sqlite3.BLOB {
+					b := arg[0].RawBlob()
+					from, to := substrBounds(len(b), arg[1].Int64(), count)
+					ctx.ResultBlob(b[from:to])
+					return
+				}
+				runes := []rune(arg[0].Text())
+				from, to := substrBounds(len(runes), arg[1].Int64(), count)
+				ctx.ResultText(string(runes[from:to]))
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// pg_left(string, n) / pg_right(string, n) -> the first / last n characters,
+	// or for negative n all but the last / first -n characters
+	for name, fromEnd := range map[string]bool{"pg_left": false, "pg_right": true} {
+		err = conn.CreateFunction(name, 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+				runes := []rune(arg[0].Text())
+				size := int64(len(runes))
+				n := arg[1].Int64()
+				if n < 0 {
+					n = max(size+n, 0)
+				}
+				n = min(n, size)
+				if fromEnd {
+					ctx.ResultText(string(runes[size-n:]))
+				} else {
+					ctx.ResultText(string(runes[:n]))
+				}
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// pg_overlay(string, replacement, from [, count]) -> string with count
+	// characters (default: the replacement's length) from position from replaced;
+	// target of overlay(string PLACING replacement FROM from [FOR count])
+	for _, nArg := range []int{3, 4} {
+		err = conn.CreateFunction("pg_overlay", nArg, sqlite3.DETERMINISTIC,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				for _, a := range arg {
+					if a.Type() == sqlite3.NULL {
+						ctx.ResultNull()
+						return
+					}
+				}
+				runes := []rune(arg[0].Text())
+				repl := arg[1].Text()
+				start := arg[2].Int64()
+				count := int64(len([]rune(repl)))
+				if len(arg) == 4 {
+					count = arg[3].Int64()
+				}
+				if start < 1 {
+					ctx.ResultError(errors.New("negative substring length not allowed"))
+					return
+				}
+				n := int64(len(runes))
+				head := min(start-1, n)
+				tail := min(max(start+count, 1)-1, n)
+				ctx.ResultText(string(runes[:head]) + repl + string(runes[tail:]))
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// pg_format(fmt, args...) -> fmt with its %s, %I, %L and %% specifiers
+	// expanded (see pgFormat); target of format
+	err = conn.CreateFunction("pg_format", -1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if len(arg) == 0 || arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			args := make([]*string, len(arg)-1)
+			for i, a := range arg[1:] {
+				if a.Type() != sqlite3.NULL {
+					s := a.Text()
+					args[i] = &s
+				}
+			}
+			s, err := pgFormat(arg[0].Text(), args)
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultText(s)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_translate(string, from, to) -> string with each character of from
+	// replaced by the one at the same position in to, or deleted when to is
+	// shorter; the first occurrence of a repeated character in from wins
+	err = conn.CreateFunction("pg_translate", 3, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+			}
+			from, to := []rune(arg[1].Text()), []rune(arg[2].Text())
+			mapping := make(map[rune]rune, len(from))
+			for i, r := range from {
+				if _, ok := mapping[r]; ok {
+					continue
+				}
+				mapping[r] = -1
+				if i < len(to) {
+					mapping[r] = to[i]
+				}
+			}
+			var b strings.Builder
+			for _, r := range arg[0].Text() {
+				switch m, ok := mapping[r]; {
+				case !ok:
+					b.WriteRune(r)
+				case m >= 0:
+					b.WriteRune(m)
+				}
+			}
+			ctx.ResultText(b.String())
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_starts_with(string, prefix) -> 1 if string begins with prefix, 0 otherwise
+	err = conn.CreateFunction("pg_starts_with", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			ctx.ResultBool(strings.HasPrefix(arg[0].Text(), arg[1].Text()))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_json_path(path) -> the SQLite JSONPath for a PG text[] path such as
+	// '{a,b}'; used by jsonb_set when the path is not a literal
+	err = conn.CreateFunction("pg_json_path", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			elems, err := parseTextArray(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultText(pgJSONPath(elems))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_jsonb_insert(target, path, value, insert_after) -> target with value
+	// inserted at the PG path (a text[] literal or JSON array of keys),
+	// shifting array elements and refusing to replace an existing key;
+	// target of jsonb_insert
+	err = conn.CreateFunction("pg_jsonb_insert", 4, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+			}
+			var path []string
+			if err := json.Unmarshal([]byte(arg[1].Text()), &path); err != nil {
+				if path, err = parseTextArray(arg[1].Text()); err != nil {
+					ctx.ResultError(err)
+					return
+				}
+			}
+			doc, err := jsonbInsert([]byte(arg[0].Text()), path, 0, []byte(arg[2].Text()), arg[3].Bool())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			ctx.ResultRawText(doc)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_array_get(array, i [, j...]) -> the element at PG's 1-based indexes,
+	// or NULL when one is out of range; pg_array_slice(array, lo, hi [, lo2,
+	// hi2...]) -> the elements from lo to hi in each dimension as a JSON
+	// array. Arrays are JSON arrays or PG array literals; targets of arr[i]
+	// and arr[lo:hi]
+	err = conn.CreateFunction("pg_array_get", -1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if len(arg) < 2 {
+				ctx.ResultError(errors.New("pg_array_get requires an array and an index"))
+				return
+			}
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+			}
+			arr, err := parseArrayValue(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			var elem any = arr
+			for _, a := range arg[1:] {
+				sub, ok := elem.([]any)
+				i := a.Int64()
+				if !ok || i < 1 || i > int64(len(sub)) {
+					ctx.ResultNull()
+					return
+				}
+				elem = sub[i-1]
+			}
+			switch v := elem.(type) {
+			case nil:
+				ctx.ResultNull()
+			case string:
+				ctx.ResultText(v)
+			case bool:
+				ctx.ResultBool(v)
+			case json.Number:
+				if n, err := v.Int64(); err == nil {
+					ctx.ResultInt64(n)
+				} else {
+					f, _ := v.Float64()
+					ctx.ResultFloat(f)
+				}
+			default:
+				b, _ := json.Marshal(v)
+				ctx.ResultRawText(b)
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = conn.CreateFunction("pg_array_slice", -1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if len(arg) < 3 || len(arg)%2 == 0 {
+				ctx.ResultError(errors.New("pg_array_slice requires an array and pairs of bounds"))
+				return
+			}
+			for _, a := range arg {
+				if a.Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+			}
+			arr, err := parseArrayValue(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			bounds := make([]int64, len(arg)-1)
+			for k, a := range arg[1:] {
+				bounds[k] = a.Int64()
+			}
+			b, _ := json.Marshal(sliceArray(arr, bounds))
+			ctx.ResultRawText(b)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_array_length(array, dim) -> length of the array's dimension dim, NULL
+	// for an empty array or a dimension it lacks; pg_cardinality(array) -> total
+	// number of elements. Arrays are JSON arrays (as array_agg builds them) or
+	// PG array literals such as '{1,2,3}'.
+	err = conn.CreateFunction("pg_array_length", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			dims, err := arrayDims(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			if dim := arg[1].Int64(); dim >= 1 && dim <= int64(len(dims)) {
+				ctx.ResultInt(dims[dim-1])
+				return
+			}
+			ctx.ResultNull()
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = conn.CreateFunction("pg_cardinality", 1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			dims, err := arrayDims(arg[0].Text())
+			if err != nil {
+				ctx.ResultError(err)
+				return
+			}
+			n := 0
+			if len(dims) > 0 {
+				n = 1
+				for _, d := range dims {
+					n *= d
+				}
+			}
+			ctx.ResultInt(n)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_repeat(string, n) -> string repeated n times (empty for n <= 0). A
+	// result longer than SQLite's string length limit is an error, as PG's
+	// is past 1GB.
+	err = conn.CreateFunction("pg_repeat", 2, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			s, n := arg[0].Text(), arg[1].Int64()
+			if n <= 0 || s == "" {
+				ctx.ResultText("")
+				return
+			}
+			if limit := int64(ctx.Conn().Limit(sqlite3.LIMIT_LENGTH, -1)); n > limit/int64(len(s)) {
+				ctx.ResultError(errors.New("requested length too large"))
+				return
+			}
+			ctx.ResultText(strings.Repeat(s, int(n)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_chr(code) -> character with the given Unicode code point
+	err = conn.CreateFunction("pg_chr", 1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			code := arg[0].Int64()
+			if code == 0 {
+				ctx.ResultError(errors.New("null character not permitted"))
+				return
+			}
+			if code < 0 || code > utf8.MaxRune || !utf8.ValidRune(rune(code)) {
+				ctx.ResultError(fmt.Errorf("requested character not valid for encoding: %d", code))
+				return
+			}
+			ctx.ResultText(string(rune(code)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_ascii(string) -> code point of the first character (0 for empty string)
+	err = conn.CreateFunction("pg_ascii", 1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			r, _ := utf8.DecodeRuneInString(arg[0].Text())
+			if r == utf8.RuneError {
+				ctx.ResultInt64(0)
+				return
+			}
+			ctx.ResultInt64(int64(r))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_greatest(a, b, ...) / pg_least(a, b, ...) -> max/min of the non-NULL
+	// arguments (NULL only if every argument is NULL)
+	err = conn.CreateFunction("pg_greatest", -1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			pickExtreme(ctx, arg, 1)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = conn.CreateFunction("pg_least", -1, sqlite3.DETERMINISTIC,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			pickExtreme(ctx, arg, -1)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// power(x, y) -> x raised to y, the target of the ^ operator. Registered so it
+	// exists even when SQLite is built without its math functions.
+	err = conn.CreateFunction("power", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			ctx.ResultFloat(math.Pow(arg[0].Float(), arg[1].Float()))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_bitxor(a, b) -> bitwise XOR of two integers, the target of PG's # operator
+	err = conn.CreateFunction("pg_bitxor", 2, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			if arg[0].Type() == sqlite3.NULL || arg[1].Type() == sqlite3.NULL {
+				ctx.ResultNull()
+				return
+			}
+			ctx.ResultInt64(arg[0].Int64() ^ arg[1].Int64())
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_num_nulls(a, b, ...) / pg_num_nonnulls(a, b, ...) -> count of NULL /
+	// non-NULL arguments. INNOCUOUS so they can be used in CHECK constraints.
+	err = conn.CreateFunction("pg_num_nulls", -1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultInt64(int64(countNulls(arg)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = conn.CreateFunction("pg_num_nonnulls", -1, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultInt64(int64(len(arg) - countNulls(arg)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Advisory locks: session-level locks keyed by one bigint or two ints, held
+	// in-process (see advisory.go). The connection is the session. Waiting for
+	// a lock ends with an error when the statement's context is cancelled.
+	lockKey := func(arg []sqlite3.Value) int64 {
+		if len(arg) == 2 {
+			return advisoryKey(arg[0].Int64(), arg[1].Int64())
+		}
+		return arg[0].Int64()
+	}
+	for _, nArg := range []int{1, 2} {
+		err = conn.CreateFunction("pg_advisory_lock", nArg, 0,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				interrupt := ctx.Conn().GetInterrupt()
+				if interrupt == nil {
+					interrupt = context.Background()
+				}
+				if err := getAdvisoryLock(lockKey(arg)).acquire(interrupt, conn); err != nil {
+					ctx.ResultError(err)
+					return
+				}
+				ctx.ResultNull()
+			},
+		)
+		if err != nil {
+			return err
+		}
+		err = conn.CreateFunction("pg_try_advisory_lock", nArg, 0,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				ctx.ResultBool(getAdvisoryLock(lockKey(arg)).tryAcquire(conn))
+			},
+		)
+		if err != nil {
+			return err
+		}
+		err = conn.CreateFunction("pg_advisory_unlock", nArg, 0,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				ctx.ResultBool(getAdvisoryLock(lockKey(arg)).release(conn))
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	// pg_notify(channel, payload) -> NULL; queues the notification for Listen
+	// subscribers (see notify.go). NOTIFY statements are translated to it.
+	err = conn.CreateFunction("pg_notify", 2, 0,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			notify(conn, Notification{Channel: arg[0].Text(), Payload: arg[1].Text()})
+			ctx.ResultNull()
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_clock_timestamp() -> the current UTC time with microseconds, read
+	// afresh on every call; target of clock_timestamp()
+	err = conn.CreateFunction("pg_clock_timestamp", 0, sqlite3.INNOCUOUS,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			ctx.ResultText(time.Now().UTC().Format("2006-01-02 15:04:05.999999"))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// pg_sleep(seconds) -> NULL after waiting; it returns early with an error
+	// when the statement's context is cancelled.
+	err = conn.CreateFunction("pg_sleep", 1, 0,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			interrupt := ctx.Conn().GetInterrupt()
+			if interrupt == nil {
+				interrupt = context.Background()
+			}
+			timer := time.NewTimer(time.Duration(arg[0].Float() * float64(time.Second)))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				ctx.ResultNull()
+			case <-interrupt.Done():
+				ctx.ResultError(interrupt.Err())
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = conn.CreateFunction("pg_advisory_unlock_all", 0, 0,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			releaseAdvisoryLocks(conn)
+			ctx.ResultNull()
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return registerMathFallbacks(conn)
+}
+
+// mathFallback is a pg_* stand-in for a math function SQLite may be built
+// without. probe calls the native function, to see whether it exists.
+type mathFallback struct {
+	name  string
+	probe string
+	nArg  int // -1 for one or two arguments
+	fn    func(x ...float64) (float64, error)
+}
+
+// mathFallbacks are keyed by the PG function name. Those with a limited domain
+// (ln, log, sqrt, asin, acos) raise PG's errors outside it where SQLite's
+// return NULL, but are only used when SQLite lacks its own.
+var mathFallbacks = map[string]mathFallback{
+	"cbrt": {"pg_cbrt", "cbrt(1)", 1, func(x ...float64) (float64, error) {
+		return math.Cbrt(x[0]), nil
+	}},
+	"ln": {"pg_ln", "ln(1)", 1, func(x ...float64) (float64, error) {
+		return pgLog(math.E, x[0])
+	}},
+	"log":   {"pg_log", "log(2, 8) + log(1)", -1, logFallback},
+	"log10": {"pg_log", "log10(1)", -1, logFallback},
+	"exp": {"pg_exp", "exp(0)", 1, func(x ...float64) (float64, error) {
+		return math.Exp(x[0]), nil
+	}},
+	"sign": {"pg_sign", "sign(1)", 1, func(x ...float64) (float64, error) {
+		switch {
+		case x[0] > 0:
+			return 1, nil
+		case x[0] < 0:
+			return -1, nil
+		}
+		return 0, nil
+	}},
+	"sqrt": {"pg_sqrt", "sqrt(1)", 1, func(x ...float64) (float64, error) {
+		if x[0] < 0 {
+			return 0, errors.New("cannot take square root of a negative number")
+		}
+		return math.Sqrt(x[0]), nil
+	}},
+
+	"sin":  {"pg_sin", "sin(0)", 1, trigFallback(math.Sin)},
+	"cos":  {"pg_cos", "cos(0)", 1, trigFallback(math.Cos)},
+	"tan":  {"pg_tan", "tan(0)", 1, trigFallback(math.Tan)},
+	"asin": {"pg_asin", "asin(0)", 1, inverseTrigFallback(math.Asin)},
+	"acos": {"pg_acos", "acos(0)", 1, inverseTrigFallback(math.Acos)},
+	"atan": {"pg_atan", "atan(0)", 1, trigFallback(math.Atan)},
+	"atan2": {"pg_atan2", "atan2(1, 1)", 2, func(x ...float64) (float64, error) {
+		return math.Atan2(x[0], x[1]), nil
+	}},
+	"degrees": {"pg_degrees", "degrees(1)", 1, func(x ...float64) (float64, error) {
+		return x[0] * 180 / math.Pi, nil
+	}},
+	"radians": {"pg_radians", "radians(1)", 1, func(x ...float64) (float64, error) {
+		return x[0] * math.Pi / 180, nil
+	}},
+	"pi": {"pg_pi", "pi()", 0, func(x ...float64) (float64, error) {
+		return math.Pi, nil
+	}},
+}
+
+// trigFallback wraps a one-argument math function as a mathFallback.fn.
+func trigFallback(f func(float64) float64) func(x ...float64) (float64, error) {
+	return func(x ...float64) (float64, error) {
+		return f(x[0]), nil
+	}
+}
+
+// inverseTrigFallback is trigFallback for asin and acos, which PG rejects
+// arguments outside [-1, 1] for.
+func inverseTrigFallback(f func(float64) float64) func(x ...float64) (float64, error) {
+	return func(x ...float64) (float64, error) {
+		if x[0] < -1 || x[0] > 1 {
+			return 0, errors.New("input is out of range")
+		}
+		return f(x[0]), nil
+	}
+}
+
+// logFallback is pg_log: log(x) is base 10 and log(b, x) base b.
+func logFallback(x ...float64) (float64, error) {
+	if len(x) == 1 {
+		return pgLog(10, x[0])
+	}
+	return pgLog(x[0], x[1])
+}
+
+// pgLog returns the base-b logarithm of x, with PG's errors for a zero or
+// negative x and for base 1.
+func pgLog(b, x float64) (float64, error) {
+	switch {
+	case x == 0 || b == 0:
+		return 0, errors.New("cannot take logarithm of zero")
+	case x < 0 || b < 0:
+		return 0, errors.New("cannot take logarithm of a negative number")
+	case b == 1:
+		return 0, errors.New("division by zero")
+	}
+	return math.Log(x) / math.Log(b), nil
+}
+
+// missingMath holds the names of the mathFallbacks SQLite lacks, found once
+// by probing a scratch connection.
+var missingMath struct {
+	once  sync.Once
+	names map[string]bool
+}
+
+// mathFuncMissing reports whether SQLite lacks the math function name, so
+// that its pg_* fallback stands in for it.
+func mathFuncMissing(name string) bool {
+	if _, ok := mathFallbacks[name]; !ok {
+		return false
+	}
+	missingMath.once.Do(func() {
+		missingMath.names = make(map[string]bool)
+		conn, err := sqlite3.Open(":memory:")
+		if err != nil {
+			return // assume the native functions are there
+		}
+		defer conn.Close()
+		for pgName, f := range mathFallbacks {
+			if stmt, _, err := conn.Prepare("SELECT " + f.probe); err != nil {
+				missingMath.names[pgName] = true
+			} else {
+				stmt.Close()
+			}
+		}
+	})
+	return missingMath.names[name]
+}
+
+// registerMathFallbacks registers the pg_* fallbacks of the math functions
+// SQLite lacks; translateFuncAliases sends calls to them.
+func registerMathFallbacks(conn *sqlite3.Conn) error {
+	for pgName, f := range mathFallbacks {
+		if !mathFuncMissing(pgName) {
+			continue
+		}
+		err := conn.CreateFunction(f.name, f.nArg, sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				if f.nArg < 0 && len(arg) != 1 && len(arg) != 2 {
+					ctx.ResultError(fmt.Errorf("%s() takes 1 or 2 arguments", pgName))
+					return
+				}
+				x := make([]float64, len(arg))
+				for i, a := range arg {
+					if a.Type() == sqlite3.NULL {
+						ctx.ResultNull()
+						return
+					}
+					x[i] = a.Float()
+				}
+				v, err := f.fn(x...)
+				if err != nil {
+					ctx.ResultError(err)
+					return
+				}
+				ctx.ResultFloat(v)
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultSettings are the run-time parameters current_setting reports when the
+// DSN does not set them, with PG's defaults.
+var defaultSettings = map[string]string{
+	"application_name": "",
+	"search_path":      `"$user", public`,
+}
+
+// registerSettings registers current_setting(name [, missing_ok]) on a
+// connection, reporting the parameters set in its DSN (see connOptions) or
+// their defaults. SQLite has no schemas, so search_path is reported but has no
+// effect on name resolution.
+func registerSettings(conn *sqlite3.Conn, settings map[string]string) error {
+	for _, nArg := range []int{1, 2} {
+		err := conn.CreateFunction("current_setting", nArg, sqlite3.DETERMINISTIC,
+			func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+				if arg[0].Type() == sqlite3.NULL {
+					ctx.ResultNull()
+					return
+				}
+				name := strings.ToLower(arg[0].Text())
+				if v, ok := settings[name]; ok {
+					ctx.ResultText(v)
+				} else if v, ok := defaultSettings[name]; ok {
+					ctx.ResultText(v)
+				} else if len(arg) == 2 && arg[1].Bool() {
+					ctx.ResultNull()
+				} else {
+					ctx.ResultError(fmt.Errorf("unrecognized configuration parameter %q", name))
+				}
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countNulls returns how many of args are SQL NULL.
+func countNulls(args []sqlite3.Value) int {
+	n := 0
+	for _, v := range args {
+		if v.Type() == sqlite3.NULL {
+			n++
+		}
+	}
+	return n
+}
+
+// pickExtreme sets the result to the non-NULL argument that compares furthest
+// in direction dir (1 for greatest, -1 for least), skipping NULLs like PostgreSQL.
+func pickExtreme(ctx sqlite3.Context, args []sqlite3.Value, dir int) {
+	best := -1
+	for i, v := range args {
+		if v.Type() == sqlite3.NULL {
+			continue
+		}
+		if best < 0 || compareValues(v, args[best])*dir > 0 {
+			best = i
+		}
+	}
+	if best < 0 {
+		ctx.ResultNull()
+		return
+	}
+	ctx.ResultValue(args[best])
+}
+
+// compareValues compares two non-NULL values numerically when both are
+// numbers, and as text otherwise.
+func compareValues(a, b sqlite3.Value) int {
+	isNum := func(v sqlite3.Value) bool {
+		return v.Type() == sqlite3.INTEGER || v.Type() == sqlite3.FLOAT
+	}
+	if isNum(a) && isNum(b) {
+		if a.Type() == sqlite3.INTEGER && b.Type() == sqlite3.INTEGER {
+			return cmp.Compare(a.Int64(), b.Int64())
+		}
+		return cmp.Compare(a.Float(), b.Float())
+	}
+	return strings.Compare(a.Text(), b.Text())
+}
 
 // parseDateTime parses a datetime string in common SQLite/ISO formats.
 func parseDateTime(s string) (time.Time, error) {
@@ -186,77 +1576,805 @@ func parseDateTime(s string) (time.Time, error) {
 		"2006-01-02",
 		"15:04:05",
 	}
-	for _, f := range formats {
-		if t, err := time.Parse(f, s); err == nil {
-			return t, nil
+	for _, f := range formats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q", s)
+}
+
+// formatPGStyle formats a time using PostgreSQL format patterns.
+func formatPGStyle(t time.Time, pgFmt string) string {
+	months := []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	monthsShort := []string{"", "Jan", "Feb", "Mar", "Apr", "May", "Jun",
+		"Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	days := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	daysShort := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+	r := strings.NewReplacer(
+		"YYYY", fmt.Sprintf("%04d", t.Year()),
+		"YY", fmt.Sprintf("%02d", t.Year()%100),
+		"Month", months[t.Month()],
+		"MONTH", strings.ToUpper(months[t.Month()]),
+		"month", strings.ToLower(months[t.Month()]),
+		"Mon", monthsShort[t.Month()],
+		"MON", strings.ToUpper(monthsShort[t.Month()]),
+		"mon", strings.ToLower(monthsShort[t.Month()]),
+		"MM", fmt.Sprintf("%02d", t.Month()),
+		"Day", days[t.Weekday()],
+		"DAY", strings.ToUpper(days[t.Weekday()]),
+		"day", strings.ToLower(days[t.Weekday()]),
+		"Dy", daysShort[t.Weekday()],
+		"DY", strings.ToUpper(daysShort[t.Weekday()]),
+		"dy", strings.ToLower(daysShort[t.Weekday()]),
+		"DD", fmt.Sprintf("%02d", t.Day()),
+		"HH24", fmt.Sprintf("%02d", t.Hour()),
+		"HH12", fmt.Sprintf("%02d", (t.Hour()+11)%12+1),
+		"HH", fmt.Sprintf("%02d", t.Hour()),
+		"MI", fmt.Sprintf("%02d", t.Minute()),
+		"SS", fmt.Sprintf("%02d", t.Second()),
+		"AM", map[bool]string{true: "AM", false: "PM"}[t.Hour() < 12],
+		"PM", map[bool]string{true: "AM", false: "PM"}[t.Hour() < 12],
+		"Q", fmt.Sprintf("%d", (int(t.Month())-1)/3+1),
+	)
+	return r.Replace(pgFmt)
+}
+
+// isPGNumericFormat reports whether pgFmt is a numeric to_char template such as
+// '9G999D99' rather than a date/time one.
+func isPGNumericFormat(pgFmt string) bool {
+	f := strings.ToUpper(pgFmt)
+	f = strings.TrimPrefix(f, "FM")
+	if !strings.ContainsAny(f, "90") {
+		return false
+	}
+	for _, c := range f {
+		if !strings.ContainsRune("90.,DGLS ", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPGNumber formats n using a PostgreSQL numeric to_char template. Supported
+// patterns are 9 and 0 (digits), . and D (decimal point), , and G (group
+// separator), L (currency symbol), S (sign) and the FM prefix (no padding).
+// There is no locale support: L, D and G always render as "$", "." and ",".
+// As in PostgreSQL, digits that don't fit are printed as '#' and, without S, a
+// space (or '-') is reserved for the sign in front of the first digit.
+// Infinity and NaN have no digits to show, so every digit is a '#'.
+func formatPGNumber(n float64, pgFmt string) string {
+	fm := false
+	if strings.HasPrefix(strings.ToUpper(pgFmt), "FM") {
+		fm = true
+		pgFmt = pgFmt[2:]
+	}
+	f := strings.ToUpper(pgFmt)
+
+	intSlots, fracSlots := 0, 0
+	seenPoint, leadingSign, trailingSign := false, false, false
+	for _, c := range f {
+		switch c {
+		case '9', '0':
+			if seenPoint {
+				fracSlots++
+			} else {
+				intSlots++
+			}
+		case '.', 'D':
+			seenPoint = true
+		case 'S':
+			if intSlots+fracSlots == 0 {
+				leadingSign = true
+			} else {
+				trailingSign = true
+			}
+		}
+	}
+
+	neg := n < 0
+	var intPart, fracPart string
+	overflow := math.IsInf(n, 0) || math.IsNaN(n)
+	if !overflow {
+		digits := strconv.FormatFloat(math.Abs(n), 'f', fracSlots, 64)
+		intPart, fracPart, _ = strings.Cut(digits, ".")
+		if intPart == "0" {
+			intPart = ""
+		}
+		overflow = len(intPart) > intSlots
+	}
+
+	signChar := func() string {
+		switch {
+		case neg:
+			return "-"
+		case leadingSign || trailingSign:
+			return "+"
+		case fm:
+			return ""
+		default:
+			return " "
+		}
+	}
+
+	var b strings.Builder
+	started := false // a digit or the decimal point has been written
+	start := func() {
+		if !started {
+			started = true
+			if !trailingSign {
+				b.WriteString(signChar())
+			}
+		}
+	}
+	intPos, fracPos := 0, 0
+	seenPoint = false
+	for i := 0; i < len(f); i++ {
+		switch c := f[i]; c {
+		case '9', '0':
+			if overflow {
+				start()
+				b.WriteByte('#')
+				continue
+			}
+			if seenPoint {
+				d := fracPart[fracPos]
+				fracPos++
+				if fm && c == '9' && strings.Trim(fracPart[fracPos-1:], "0") == "" {
+					continue
+				}
+				b.WriteByte(d)
+				continue
+			}
+			idx := intPos - (intSlots - len(intPart))
+			intPos++
+			switch {
+			case idx >= 0:
+				start()
+				b.WriteByte(intPart[idx])
+			case c == '0' || started:
+				start()
+				b.WriteByte('0')
+			case !fm:
+				b.WriteByte(' ')
+			}
+		case '.', 'D':
+			start()
+			seenPoint = true
+			b.WriteByte('.')
+		case ',', 'G':
+			if started {
+				b.WriteByte(',')
+			} else if !fm {
+				b.WriteByte(' ')
+			}
+		case 'L':
+			b.WriteByte('$')
+		case 'S':
+			if trailingSign {
+				b.WriteString(signChar())
+			}
+		default:
+			b.WriteByte(pgFmt[i])
+		}
+	}
+	return b.String()
+}
+
+// numericArg returns a numeric argument as a float64, reporting whether it is
+// an integer. Text is parsed as a number, as PG would for an unknown literal.
+func numericArg(v sqlite3.Value) (float64, bool) {
+	switch v.Type() {
+	case sqlite3.INTEGER:
+		return float64(v.Int64()), true
+	case sqlite3.TEXT:
+		s := strings.TrimSpace(v.Text())
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return float64(n), true
+		}
+		f, _ := strconv.ParseFloat(s, 64)
+		return f, false
+	}
+	return v.Float(), false
+}
+
+// pgFormat implements PG's format(): %s inserts an argument as text (NULL as
+// nothing), %I as an identifier quoted where needed (see quoteIdent) and %L as
+// a quoted literal (NULL unquoted). A specifier may name its argument by
+// position (%2$s) and give a width, left-aligned with - (%-10s); %% is a
+// literal percent sign.
+func pgFormat(format string, args []*string) (string, error) {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+
+		// [n$][-][width]type
+		spec := i
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+		if i < len(format) && format[i] == '$' && i > spec {
+			n, _ := strconv.Atoi(format[spec:i])
+			if n == 0 {
+				return "", errors.New("format specifies argument 0, but arguments are numbered from 1")
+			}
+			next = n - 1
+			i++
+			spec = i
+		} else {
+			i = spec
+		}
+		left := i < len(format) && format[i] == '-'
+		if left {
+			i++
+		}
+		width := 0
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			width = width*10 + int(format[i]-'0')
+			i++
+		}
+		if i >= len(format) {
+			return "", errors.New("unterminated format() type specifier")
+		}
+		if next >= len(args) {
+			return "", errors.New("too few arguments for format()")
+		}
+		arg := args[next]
+		next++
+
+		var v string
+		switch format[i] {
+		case 's':
+			if arg != nil {
+				v = *arg
+			}
+		case 'I':
+			if arg == nil {
+				return "", errors.New("null values cannot be formatted as an SQL identifier")
+			}
+			v = quoteIdent(*arg)
+		case 'L':
+			v = "NULL"
+			if arg != nil {
+				v = quoteLiteral(*arg)
+			}
+		default:
+			return "", fmt.Errorf("unrecognized format() type specifier %q", format[i])
+		}
+		pad := strings.Repeat(" ", max(width-utf8.RuneCountInString(v), 0))
+		if left {
+			b.WriteString(v + pad)
+		} else {
+			b.WriteString(pad + v)
 		}
 	}
-	return time.Time{}, fmt.Errorf("cannot parse %q", s)
+	return b.String(), nil
 }
 
-// formatPGStyle formats a time using PostgreSQL format patterns.
-func formatPGStyle(t time.Time, pgFmt string) string {
-	months := []string{"", "January", "February", "March", "April", "May", "June",
-		"July", "August", "September", "October", "November", "December"}
-	monthsShort := []string{"", "Jan", "Feb", "Mar", "Apr", "May", "Jun",
-		"Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
-	days := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
-	daysShort := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+// pgReservedWords are the keywords PG's quote_ident quotes: the reserved ones
+// and those that can only be column or function names.
+var pgReservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "authorization": true,
+	"between": true, "bigint": true, "binary": true, "bit": true, "boolean": true,
+	"both": true, "case": true, "cast": true, "char": true, "character": true,
+	"check": true, "coalesce": true, "collate": true, "collation": true, "column": true,
+	"concurrently": true, "constraint": true, "create": true, "cross": true,
+	"current_catalog": true, "current_date": true, "current_role": true,
+	"current_schema": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "dec": true, "decimal": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true, "else": true,
+	"end": true, "except": true, "exists": true, "extract": true, "false": true,
+	"fetch": true, "float": true, "for": true, "foreign": true, "freeze": true,
+	"from": true, "full": true, "grant": true, "greatest": true, "group": true,
+	"grouping": true, "having": true, "ilike": true, "in": true, "initially": true,
+	"inner": true, "inout": true, "int": true, "integer": true, "intersect": true,
+	"interval": true, "into": true, "is": true, "isnull": true, "join": true,
+	"lateral": true, "leading": true, "least": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "national": true,
+	"natural": true, "nchar": true, "none": true, "normalize": true, "not": true,
+	"notnull": true, "null": true, "nullif": true, "numeric": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "out": true, "outer": true,
+	"overlaps": true, "overlay": true, "placing": true, "position": true,
+	"precision": true, "primary": true, "real": true, "references": true,
+	"returning": true, "right": true, "row": true, "select": true,
+	"session_user": true, "setof": true, "similar": true, "smallint": true,
+	"some": true, "substring": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "time": true,
+	"timestamp": true, "to": true, "trailing": true, "treat": true, "trim": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"values": true, "varchar": true, "variadic": true, "verbose": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
 
-	r := strings.NewReplacer(
-		"YYYY", fmt.Sprintf("%04d", t.Year()),
-		"YY", fmt.Sprintf("%02d", t.Year()%100),
-		"Month", months[t.Month()],
-		"MONTH", strings.ToUpper(months[t.Month()]),
-		"month", strings.ToLower(months[t.Month()]),
-		"Mon", monthsShort[t.Month()],
-		"MON", strings.ToUpper(monthsShort[t.Month()]),
-		"mon", strings.ToLower(monthsShort[t.Month()]),
-		"MM", fmt.Sprintf("%02d", t.Month()),
-		"Day", days[t.Weekday()],
-		"DAY", strings.ToUpper(days[t.Weekday()]),
-		"day", strings.ToLower(days[t.Weekday()]),
-		"Dy", daysShort[t.Weekday()],
-		"DY", strings.ToUpper(daysShort[t.Weekday()]),
-		"dy", strings.ToLower(daysShort[t.Weekday()]),
-		"DD", fmt.Sprintf("%02d", t.Day()),
-		"HH24", fmt.Sprintf("%02d", t.Hour()),
-		"HH12", fmt.Sprintf("%02d", (t.Hour()+11)%12+1),
-		"HH", fmt.Sprintf("%02d", t.Hour()),
-		"MI", fmt.Sprintf("%02d", t.Minute()),
-		"SS", fmt.Sprintf("%02d", t.Second()),
-		"AM", map[bool]string{true: "AM", false: "PM"}[t.Hour() < 12],
-		"PM", map[bool]string{true: "AM", false: "PM"}[t.Hour() < 12],
-		"Q", fmt.Sprintf("%d", (int(t.Month())-1)/3+1),
-	)
-	return r.Replace(pgFmt)
+// quoteIdent double-quotes s, doubling embedded quotes, unless it is already a
+// valid unquoted identifier: lower case letters, digits and underscores, not
+// starting with a digit and not a reserved word.
+func quoteIdent(s string) string {
+	plain := s != "" && !pgReservedWords[s]
+	for i, r := range s {
+		if !(r >= 'a' && r <= 'z' || r == '_' || i > 0 && (r >= '0' && r <= '9' || r == '$')) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteLiteral single-quotes s, doubling embedded quotes. As in PG, a value
+// containing backslashes is written as an E'...' string with them doubled.
+func quoteLiteral(s string) string {
+	q := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.Contains(s, `\`) {
+		return "E" + strings.ReplaceAll(q, `\`, `\\`)
+	}
+	return q
+}
+
+// regexpMatches returns the capture groups of pattern's first match in str as a
+// JSON array, the whole match when pattern has no groups, and false when there
+// is no match. Groups that did not take part in the match are null. Of PG's
+// flags, i (case-insensitive), c (case-sensitive) and g are accepted; with g PG
+// returns one row per match, but only the first is reported here.
+func regexpMatches(str, pattern, flags string) (string, bool, error) {
+	for _, f := range flags {
+		switch f {
+		case 'i':
+			pattern = "(?i)" + pattern
+		case 'c', 'g':
+		default:
+			return "", false, fmt.Errorf("invalid regular expression option: %q", f)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	m := re.FindStringSubmatchIndex(str)
+	if m == nil {
+		return "", false, nil
+	}
+	if len(m) > 2 {
+		m = m[2:]
+	}
+	groups := make([]any, 0, len(m)/2)
+	for i := 0; i < len(m); i += 2 {
+		if m[i] < 0 {
+			groups = append(groups, nil)
+		} else {
+			groups = append(groups, str[m[i]:m[i+1]])
+		}
+	}
+	b, err := json.Marshal(groups)
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
 }
 
 // convertSimilarToRegex converts a SQL SIMILAR TO pattern to a Go regex.
-// SIMILAR TO uses: % (any string), _ (any char), | (alternation), () (grouping).
-func convertSimilarToRegex(pattern string) string {
+// SIMILAR TO uses % (any string) and _ (any char), and shares | (alternation),
+// () (grouping), * + ? {m,n} (repetition) and [...] (character classes) with
+// regular expressions. The escape character, if not empty, makes the next
+// character literal.
+func convertSimilarToRegex(pattern, escape string) (string, error) {
+	var esc rune = -1
+	switch utf8.RuneCountInString(escape) {
+	case 0:
+	case 1:
+		esc, _ = utf8.DecodeRuneInString(escape)
+	default:
+		return "", fmt.Errorf("invalid escape string: %q", escape)
+	}
 	var b strings.Builder
-	b.WriteString("^")
-	for _, ch := range pattern {
-		switch ch {
-		case '%':
+	b.WriteString("^(?:")
+	runes := []rune(pattern)
+	inClass := false // inside [...], where characters are taken as they are
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == esc:
+			if i+1 == len(runes) {
+				return "", fmt.Errorf("invalid regular expression: pattern ends with escape character")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case inClass:
+			if ch == ']' && runes[i-1] != '[' && !(runes[i-1] == '^' && runes[i-2] == '[') {
+				inClass = false
+			}
+			b.WriteRune(ch)
+		case ch == '[':
+			inClass = true
+			b.WriteRune(ch)
+		case ch == '%':
 			b.WriteString(".*")
-		case '_':
+		case ch == '_':
 			b.WriteString(".")
-		case '|', '(', ')':
-			b.WriteRune(ch)
-		case '.', '^', '$', '+', '?', '{', '}', '[', ']', '\\', '*':
-			b.WriteRune('\\')
+		case strings.ContainsRune("|()*+?{}]", ch):
 			b.WriteRune(ch)
 		default:
-			b.WriteRune(ch)
+			b.WriteString(regexp.QuoteMeta(string(ch)))
 		}
 	}
-	b.WriteString("$")
-	return b.String()
+	b.WriteString(")$")
+	return b.String(), nil
+}
+
+// likeFoldMatch reports whether s matches the LIKE pattern, ignoring case as
+// PG's ILIKE does: % matches any string, _ any one character, and the escape
+// character, if not empty, makes the next character literal.
+func likeFoldMatch(s, pattern, escape string) (bool, error) {
+	var esc rune = -1
+	switch utf8.RuneCountInString(escape) {
+	case 0:
+	case 1:
+		esc, _ = utf8.DecodeRuneInString(escape)
+	default:
+		return false, fmt.Errorf("invalid escape string: %q", escape)
+	}
+
+	// anyRune and anyString stand for unescaped _ and %.
+	const anyRune, anyString = -1, -2
+	var pat []rune
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch ch := runes[i]; ch {
+		case esc:
+			if i+1 == len(runes) {
+				return false, errors.New("LIKE pattern must not end with escape character")
+			}
+			i++
+			pat = append(pat, runes[i])
+		case '_':
+			pat = append(pat, anyRune)
+		case '%':
+			pat = append(pat, anyString)
+		default:
+			pat = append(pat, ch)
+		}
+	}
+
+	// Match, backtracking to the last % on a mismatch.
+	str := []rune(s)
+	si, pi := 0, 0
+	star, mark := -1, 0
+	for si < len(str) {
+		switch {
+		case pi < len(pat) && pat[pi] == anyString:
+			star, mark = pi, si
+			pi++
+		case pi < len(pat) && (pat[pi] == anyRune || foldEqual(pat[pi], str[si])):
+			si++
+			pi++
+		case star >= 0:
+			mark++
+			si, pi = mark, star+1
+		default:
+			return false, nil
+		}
+	}
+	for pi < len(pat) && pat[pi] == anyString {
+		pi++
+	}
+	return pi == len(pat), nil
+}
+
+// foldEqual reports whether a and b are equal under Unicode simple case folding.
+func foldEqual(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// tsWords splits s into lower-cased words of letters and digits.
+func tsWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tsVector writes the words of s as PG writes a tsvector: each distinct word
+// once, sorted and quoted, with the positions it occurs at, as in
+// 'a':1,4 'cat':3 'fat':2.
+func tsVector(s string) string {
+	positions := make(map[string][]string)
+	for i, w := range tsWords(s) {
+		positions[w] = append(positions[w], strconv.Itoa(i+1))
+	}
+	words := slices.Sorted(maps.Keys(positions))
+	for i, w := range words {
+		words[i] = "'" + w + "':" + strings.Join(positions[w], ",")
+	}
+	return strings.Join(words, " ")
+}
+
+// tsLexemes returns the lexemes of s, a tsvector in the form tsVector writes:
+// quoted lexemes, each optionally followed by positions and weights. It
+// reports false when s is not in that form.
+func tsLexemes(s string) ([]string, bool) {
+	var lexemes []string
+	for i := 0; ; {
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i == len(s) {
+			return lexemes, len(lexemes) > 0
+		}
+		if s[i] != '\'' {
+			return nil, false
+		}
+		var lexeme strings.Builder
+		for i++; ; i++ {
+			if i == len(s) {
+				return nil, false
+			}
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+				} else {
+					break
+				}
+			}
+			lexeme.WriteByte(s[i])
+		}
+		i++
+		if i < len(s) && s[i] == ':' {
+			for i++; i < len(s) && strings.IndexByte("0123456789,ABCD", s[i]) >= 0; i++ {
+			}
+		}
+		if i < len(s) && s[i] != ' ' {
+			return nil, false
+		}
+		lexemes = append(lexemes, lexeme.String())
+	}
+}
+
+// tsMatch reports whether the words of document satisfy query, a tsquery such
+// as 'fat & (cat | rat) & !dog'. Terms side by side are taken as joined by &,
+// as are terms joined by the phrase operator <->, so plainto_tsquery text
+// works too.
+func tsMatch(document, query string) (bool, error) {
+	words, ok := tsLexemes(document)
+	if !ok {
+		words = tsWords(document)
+	}
+	p := &tsQueryParser{query: query, words: words}
+	p.next()
+	if p.tok == "" {
+		return false, nil // an empty query matches nothing
+	}
+	matched, err := p.parseOr()
+	if err == nil && p.tok != "" {
+		err = p.syntaxError()
+	}
+	return matched, err
+}
+
+// tsQueryParser evaluates a tsquery against a document's words as it parses it.
+type tsQueryParser struct {
+	query string
+	pos   int
+	tok   string // current token: "&", "|", "!", "(", ")", a term, or "" at the end
+	words []string
+}
+
+// next reads the next token of the query.
+func (p *tsQueryParser) next() {
+	for p.pos < len(p.query) && unicode.IsSpace(rune(p.query[p.pos])) {
+		p.pos++
+	}
+	if p.pos == len(p.query) {
+		p.tok = ""
+		return
+	}
+	rest := p.query[p.pos:]
+	switch {
+	case strings.ContainsRune("&|!()", rune(rest[0])):
+		p.tok = rest[:1]
+	case rest[0] == '<':
+		// Phrase operators <-> and <n> are treated as &.
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			end = len(rest) - 1
+		}
+		p.pos += end + 1
+		p.tok = "&"
+		return
+	default:
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune("&|!()<", r)
+		})
+		if end < 0 {
+			end = len(rest)
+		}
+		p.tok = rest[:end]
+	}
+	p.pos += len(p.tok)
+}
+
+func (p *tsQueryParser) syntaxError() error {
+	return fmt.Errorf("syntax error in tsquery: %q", p.query)
+}
+
+// parseOr parses terms joined by |.
+func (p *tsQueryParser) parseOr() (bool, error) {
+	matched, err := p.parseAnd()
+	for err == nil && p.tok == "|" {
+		p.next()
+		var m bool
+		m, err = p.parseAnd()
+		matched = matched || m
+	}
+	return matched, err
+}
+
+// parseAnd parses terms joined by & or written side by side.
+func (p *tsQueryParser) parseAnd() (bool, error) {
+	matched, err := p.parseNot()
+	for err == nil && p.tok != "" && p.tok != "|" && p.tok != ")" {
+		if p.tok == "&" {
+			p.next()
+		}
+		var m bool
+		m, err = p.parseNot()
+		matched = matched && m
+	}
+	return matched, err
+}
+
+// parseNot parses a term, a parenthesized query, or ! before either.
+func (p *tsQueryParser) parseNot() (bool, error) {
+	switch p.tok {
+	case "!":
+		p.next()
+		matched, err := p.parseNot()
+		return !matched, err
+	case "(":
+		p.next()
+		matched, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if p.tok != ")" {
+			return false, p.syntaxError()
+		}
+		p.next()
+		return matched, nil
+	case "", "&", "|", ")":
+		return false, p.syntaxError()
+	}
+	term := strings.Trim(p.tok, "'")
+	p.next()
+	// A :* suffix asks for a prefix match; other : suffixes are weights.
+	prefix := false
+	if i := strings.IndexByte(term, ':'); i >= 0 {
+		prefix = strings.Contains(term[i:], "*")
+		term = term[:i]
+	}
+	// A term of several words needs all of them.
+	for _, want := range tsWords(term) {
+		if !slices.ContainsFunc(p.words, func(w string) bool {
+			return w == want || prefix && strings.HasPrefix(w, want)
+		}) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
-// generateUUIDv4 generates a random UUID v4 string.
+// normalizeUUID validates s in any of the input forms PostgreSQL accepts
+// (hyphenated or not, optionally in braces, any case) and returns the canonical
+// lowercase 8-4-4-4-12 form.
+func normalizeUUID(s string) (string, error) {
+	h := strings.TrimSpace(s)
+	if strings.HasPrefix(h, "{") && strings.HasSuffix(h, "}") {
+		h = h[1 : len(h)-1]
+	}
+	h = strings.ReplaceAll(h, "-", "")
+	raw, err := hex.DecodeString(h)
+	if err != nil || len(raw) != 16 {
+		return "", fmt.Errorf("invalid input syntax for type uuid: %q", s)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", raw[0:4], raw[4:6], raw[6:8], raw[8:10], raw[10:16]), nil
+}
+
+// encodeBytes implements PG's encode(bytea, format).
+func encodeBytes(data []byte, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "hex":
+		return hex.EncodeToString(data), nil
+	case "base64":
+		// PG breaks base64 output into lines of 76 characters.
+		enc := base64.StdEncoding.EncodeToString(data)
+		var b strings.Builder
+		for len(enc) > 76 {
+			b.WriteString(enc[:76])
+			b.WriteByte('\n')
+			enc = enc[76:]
+		}
+		b.WriteString(enc)
+		return b.String(), nil
+	case "escape":
+		// Zero bytes and bytes with the high bit set become \nnn; backslashes are doubled.
+		var b strings.Builder
+		for _, c := range data {
+			switch {
+			case c == '\\':
+				b.WriteString(`\\`)
+			case c == 0 || c >= 0x80:
+				fmt.Fprintf(&b, `\%03o`, c)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unrecognized encoding: %q", format)
+}
+
+// decodeBytes implements PG's decode(text, format).
+func decodeBytes(s, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "hex":
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hexadecimal data: %v", err)
+		}
+		return b, nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data: %v", err)
+		}
+		return b, nil
+	case "escape":
+		b := []byte{}
+		for i := 0; i < len(s); i++ {
+			if s[i] != '\\' {
+				b = append(b, s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\\' {
+				b = append(b, '\\')
+				i++
+				continue
+			}
+			if i+3 < len(s) {
+				if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+					b = append(b, byte(n))
+					i += 3
+					continue
+				}
+			}
+			return nil, errors.New("invalid input syntax for type bytea")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unrecognized encoding: %q", format)
+}
+
+// generateUUIDv4 generates a random UUID v4 string from crypto/rand, like pgcrypto.
 func generateUUIDv4() string {
 	var uuid [16]byte
 	_, _ = rand.Read(uuid[:])
@@ -265,3 +2383,286 @@ func generateUUIDv4() string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
 		uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
 }
+
+// castNumeric rounds the decimal text v to scale places, half away from zero,
+// and checks that the result fits in precision digits.
+func castNumeric(v string, precision, scale int) (string, error) {
+	s := strings.TrimSpace(v)
+	if strings.EqualFold(s, "NaN") {
+		return "NaN", nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Trim(s, "0123456789.eE+-") != "" {
+		return "", fmt.Errorf("invalid input syntax for type numeric: %q", v)
+	}
+	out := r.FloatString(scale)
+	if strings.Trim(out, "-0.") == "" {
+		out = strings.TrimPrefix(out, "-") // PG has no negative zero
+	}
+	digits := strings.TrimLeft(strings.TrimPrefix(out, "-"), "0")
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits = digits[:i]
+	}
+	if len(digits) > precision-scale {
+		return "", fmt.Errorf("numeric field overflow: a field with precision %d, scale %d must round to an absolute value less than 10^%d", precision, scale, precision-scale)
+	}
+	return out, nil
+}
+
+// jsonbInsert inserts value into doc at path[pos:], as PG's jsonb_insert does:
+// into an array before the indexed element (after it with after set), or as
+// a new object key. A path through a missing key or index leaves doc alone.
+func jsonbInsert(doc []byte, path []string, pos int, value []byte, after bool) ([]byte, error) {
+	if pos == len(path) {
+		return doc, nil
+	}
+	doc = bytes.TrimSpace(doc)
+	last := pos == len(path)-1
+	switch {
+	case bytes.HasPrefix(doc, []byte("[")):
+		var elems []json.RawMessage
+		if err := json.Unmarshal(doc, &elems); err != nil {
+			return nil, err
+		}
+		idx, err := strconv.Atoi(path[pos])
+		if err != nil {
+			return nil, fmt.Errorf("path element at position %d is not an integer: %q", pos+1, path[pos])
+		}
+		if idx < 0 {
+			idx += len(elems)
+		}
+		if !last {
+			if idx < 0 || idx >= len(elems) {
+				return doc, nil
+			}
+			if elems[idx], err = jsonbInsert(elems[idx], path, pos+1, value, after); err != nil {
+				return nil, err
+			}
+		} else {
+			if after {
+				idx++
+			}
+			idx = max(0, min(idx, len(elems)))
+			elems = slices.Insert(elems, idx, json.RawMessage(value))
+		}
+		return joinJSON('[', ']', elems), nil
+	case bytes.HasPrefix(doc, []byte("{")):
+		keys, vals, err := jsonObjectFields(doc)
+		if err != nil {
+			return nil, err
+		}
+		i := slices.Index(keys, path[pos])
+		switch {
+		case last && i >= 0:
+			return nil, errors.New("cannot replace existing key")
+		case last:
+			keys, vals = append(keys, path[pos]), append(vals, value)
+		case i < 0:
+			return doc, nil
+		default:
+			if vals[i], err = jsonbInsert(vals[i], path, pos+1, value, after); err != nil {
+				return nil, err
+			}
+		}
+		fields := make([]json.RawMessage, len(keys))
+		for i, k := range keys {
+			key, _ := json.Marshal(k)
+			fields[i] = append(append(key, ':'), vals[i]...)
+		}
+		return joinJSON('{', '}', fields), nil
+	}
+	return nil, errors.New("cannot set path in scalar")
+}
+
+// jsonObjectFields returns the keys of a JSON object and their raw values, in
+// document order.
+func jsonObjectFields(doc []byte) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	var vals []json.RawMessage
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys, vals = append(keys, tok.(string)), append(vals, v)
+	}
+	return keys, vals, nil
+}
+
+// joinJSON joins raw JSON items with commas between the open and close
+// brackets.
+func joinJSON(open, close byte, items []json.RawMessage) []byte {
+	out := []byte{open}
+	for i, it := range items {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, it...)
+	}
+	return append(out, close)
+}
+
+// parseArrayValue parses an array given as JSON or as a one-dimensional PG
+// literal. Literal elements that read as numbers become json.Number and an
+// unquoted NULL becomes nil, so they compare as PG's would.
+func parseArrayValue(s string) ([]any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var arr []any
+	if err := dec.Decode(&arr); err == nil {
+		return arr, nil
+	}
+	elems, err := parseTextArray(s)
+	if err != nil {
+		return nil, err
+	}
+	arr = make([]any, len(elems))
+	for i, e := range elems {
+		switch _, err := strconv.ParseFloat(e, 64); {
+		case strings.EqualFold(e, "NULL"):
+			arr[i] = nil
+		case err == nil:
+			arr[i] = json.Number(e)
+		default:
+			arr[i] = e
+		}
+	}
+	return arr, nil
+}
+
+// sliceArray returns the elements of arr from the 1-based bounds[0] to
+// bounds[1], clamped to the array, slicing nested arrays by the remaining
+// bounds.
+func sliceArray(arr []any, bounds []int64) []any {
+	lo, hi := max(bounds[0], 1), min(bounds[1], int64(len(arr)))
+	out := []any{}
+	for i := lo; i <= hi; i++ {
+		elem := arr[i-1]
+		if sub, ok := elem.([]any); ok && len(bounds) > 2 {
+			elem = sliceArray(sub, bounds[2:])
+		}
+		out = append(out, elem)
+	}
+	return out
+}
+
+// arrayDims returns the length of each dimension of an array given as a JSON
+// array or a one-dimensional PG array literal. An empty array has no
+// dimensions; nested arrays are taken to be regular, as PG requires.
+func arrayDims(s string) ([]int, error) {
+	var arr []any
+	if err := json.Unmarshal([]byte(s), &arr); err != nil {
+		elems, err := parseTextArray(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input syntax for type array: %q", s)
+		}
+		arr = make([]any, len(elems))
+	}
+	var dims []int
+	for len(arr) > 0 {
+		dims = append(dims, len(arr))
+		next, ok := arr[0].([]any)
+		if !ok {
+			break
+		}
+		arr = next
+	}
+	return dims, nil
+}
+
+// parseTextArray parses a one-dimensional PG array literal such as
+// {a,"b c",d} into its elements.
+func parseTextArray(s string) ([]string, error) {
+	body := strings.TrimSpace(s)
+	if !strings.HasPrefix(body, "{") || !strings.HasSuffix(body, "}") {
+		return nil, fmt.Errorf("malformed array literal: %q", s)
+	}
+	body = body[1 : len(body)-1]
+	if strings.TrimSpace(body) == "" {
+		return nil, nil
+	}
+	var elems []string
+	for {
+		body = strings.TrimLeft(body, " \t\n")
+		var elem strings.Builder
+		if strings.HasPrefix(body, `"`) {
+			i := 1
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				elem.WriteByte(body[i])
+			}
+			if i == len(body) {
+				return nil, fmt.Errorf("malformed array literal: %q", s)
+			}
+			body = strings.TrimLeft(body[i+1:], " \t\n")
+		} else {
+			i := strings.IndexByte(body, ',')
+			if i < 0 {
+				i = len(body)
+			}
+			elem.WriteString(strings.TrimSpace(body[:i]))
+			body = body[i:]
+			if elem.Len() == 0 || strings.ContainsAny(elem.String(), `{}"`) {
+				return nil, fmt.Errorf("malformed array literal: %q", s)
+			}
+		}
+		elems = append(elems, elem.String())
+		if body == "" {
+			return elems, nil
+		}
+		if body[0] != ',' {
+			return nil, fmt.Errorf("malformed array literal: %q", s)
+		}
+		body = body[1:]
+	}
+}
+
+// substrBounds returns the slice bounds within a value of n characters for PG's
+// substring from the 1-based position start, taking count characters or, when
+// count is negative, the rest of the value.
+func substrBounds(n int, start, count int64) (from, to int) {
+	end := int64(n) + 1
+	if count >= 0 && count < end-start {
+		end = start + count
+	}
+	start = max(start, 1)
+	if end <= start {
+		return 0, 0
+	}
+	return int(start - 1), int(end - 1)
+}
+
+// resultSeconds returns an interval length in seconds, as an INTEGER when it
+// is whole.
+func resultSeconds(ctx sqlite3.Context, secs float64) {
+	if secs == math.Trunc(secs) && math.Abs(secs) < 1<<53 {
+		ctx.ResultInt64(int64(secs))
+		return
+	}
+	ctx.ResultFloat(secs)
+}
+
+// parseTimestampArg parses a timestamp as SQLite's date functions write it,
+// also accepting a bare date or a time without seconds.
+func parseTimestampArg(s string) (time.Time, error) {
+	v := strings.TrimSpace(s)
+	if t, ok := tryParseTimestamp(v); ok {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid input syntax for type timestamp: %q", s)
+}
@@ -0,0 +1,47 @@
+package pglike
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// registerRandom registers pg_random() and setseed(x) on a connection. As in
+// PostgreSQL, each connection has its own generator: it starts from a random
+// seed, and setseed makes the values that follow on that connection
+// repeatable. The sequence for a given seed is not the one PG produces.
+func registerRandom(conn *sqlite3.Conn) error {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+	// pg_random() -> a double in [0, 1); target of random(). SQLite's own
+	// random() returns a 64-bit integer.
+	err := conn.CreateFunction("pg_random", 0, 0,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			mu.Lock()
+			defer mu.Unlock()
+			ctx.ResultFloat(rng.Float64())
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// setseed(x) -> NULL, seeding pg_random with x in [-1, 1].
+	return conn.CreateFunction("setseed", 1, 0,
+		func(ctx sqlite3.Context, arg ...sqlite3.Value) {
+			seed := arg[0].Float()
+			if arg[0].Type() == sqlite3.NULL || seed < -1 || seed > 1 || math.IsNaN(seed) {
+				ctx.ResultError(fmt.Errorf("setseed parameter %g is out of allowed range [-1,1]", seed))
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			rng = rand.New(rand.NewPCG(math.Float64bits(seed), 0))
+			ctx.ResultNull()
+		},
+	)
+}
@@ -0,0 +1,35 @@
+package pglike
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// WithSavepoint emulates a nested transaction inside tx: it sets SAVEPOINT name,
+// runs fn, and then releases the savepoint if fn succeeds or rolls back to it if
+// fn returns an error, leaving the outer transaction usable either way. fn's error
+// is returned, joined with any error from the rollback.
+//
+// The same statements may also be issued directly: SAVEPOINT, RELEASE [SAVEPOINT]
+// and ROLLBACK TO [SAVEPOINT] share their syntax with SQLite and pass through
+// untranslated.
+func WithSavepoint(tx *sql.Tx, name string, fn func() error) error {
+	ident := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	if _, err := tx.Exec("SAVEPOINT " + ident); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rerr := tx.Exec("ROLLBACK TO SAVEPOINT " + ident); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		// Like PG, rolling back keeps the savepoint; release it so that
+		// repeated calls do not stack savepoints.
+		if _, rerr := tx.Exec("RELEASE SAVEPOINT " + ident); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+	_, err := tx.Exec("RELEASE SAVEPOINT " + ident)
+	return err
+}
@@ -2,6 +2,7 @@ package pglike
 
 import (
 	"strings"
+	"sync"
 	"unicode"
 )
 
@@ -21,6 +22,7 @@ const (
 	TokWhitespace                  // spaces, tabs, newlines
 	TokComment                     // -- or /* */
 	TokDot                         // .
+	TokBracket                     // [ or ]
 )
 
 // Token represents a single token from SQL input.
@@ -59,11 +61,11 @@ var sqlKeywords = map[string]bool{
 	"NUMERIC": true, "DECIMAL": true,
 	"TIMESTAMP": true, "TIMESTAMPTZ": true, "DATE": true, "TIME": true, "TIMETZ": true,
 	"UUID": true, "BYTEA": true, "JSON": true, "JSONB": true, "BLOB": true,
-	"ZONE": true,
+	"ZONE": true, "CITEXT": true, "TSVECTOR": true, "TSQUERY": true,
 
 	// Function-like keywords
 	"NOW": true, "CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
-	"EXTRACT": true, "COALESCE": true, "NULLIF": true,
+	"EXTRACT": true, "COALESCE": true, "NULLIF": true, "OVERLAY": true,
 
 	// Additional
 	"REPLACE": true, "CONFLICT": true, "DO": true, "NOTHING": true,
@@ -83,7 +85,10 @@ var sqlKeywords = map[string]bool{
 	"NULLS": true, "SEQUENCE": true, "INCREMENT": true, "START": true,
 	"MINVALUE": true, "MAXVALUE": true, "CYCLE": true, "OWNED": true,
 	"EXPLAIN": true, "ANALYZE": true, "VERBOSE": true, "PLAN": true,
-	"QUERY": true,
+	"QUERY": true, "SAVEPOINT": true, "RELEASE": true, "FOR": true, "NOWAIT": true,
+	"LISTEN": true, "UNLISTEN": true, "NOTIFY": true, "PLACING": true,
+	"REFRESH": true, "TABLESAMPLE": true, "BERNOULLI": true, "SYSTEM": true,
+	"REPEATABLE": true,
 }
 
 // Tokenize splits a SQL string into tokens.
@@ -243,20 +248,21 @@ func Tokenize(sql string) []Token {
 			continue
 		}
 
-		// Regex operators !~* !~ ~*
-		if ch == '!' && i+1 < n && runes[i+1] == '~' {
-			if i+2 < n && runes[i+2] == '*' {
-				tokens = append(tokens, Token{Kind: TokOperator, Value: "!~*", Raw: "!~*"})
-				i += 3
-			} else {
-				tokens = append(tokens, Token{Kind: TokOperator, Value: "!~", Raw: "!~"})
-				i += 2
+		// Regex operators !~* !~ ~* and LIKE operators ~~ ~~* !~~ !~~*
+		if (ch == '!' && i+1 < n && runes[i+1] == '~') || (ch == '~' && i+1 < n && (runes[i+1] == '*' || runes[i+1] == '~')) {
+			start := i
+			if ch == '!' {
+				i++
 			}
-			continue
-		}
-		if ch == '~' && i+1 < n && runes[i+1] == '*' {
-			tokens = append(tokens, Token{Kind: TokOperator, Value: "~*", Raw: "~*"})
-			i += 2
+			i++ // ~
+			if i < n && runes[i] == '~' {
+				i++
+			}
+			if i < n && runes[i] == '*' {
+				i++
+			}
+			raw := string(runes[start:i])
+			tokens = append(tokens, Token{Kind: TokOperator, Value: raw, Raw: raw})
 			continue
 		}
 
@@ -272,6 +278,26 @@ func Tokenize(sql string) []Token {
 			continue
 		}
 
+		// # (bitwise XOR) and the JSON path operators #> #>> #-
+		if ch == '#' {
+			raw := "#"
+			if i+2 < n && runes[i+1] == '>' && runes[i+2] == '>' {
+				raw = "#>>"
+			} else if i+1 < n && (runes[i+1] == '>' || runes[i+1] == '-') {
+				raw = string(runes[i : i+2])
+			}
+			tokens = append(tokens, Token{Kind: TokOperator, Value: raw, Raw: raw})
+			i += len(raw)
+			continue
+		}
+
+		// Full-text match @@
+		if ch == '@' && i+1 < n && runes[i+1] == '@' {
+			tokens = append(tokens, Token{Kind: TokOperator, Value: "@@", Raw: "@@"})
+			i += 2
+			continue
+		}
+
 		// JSON operators -> ->>
 		if ch == '-' && i+1 < n && runes[i+1] == '>' {
 			if i+2 < n && runes[i+2] == '>' {
@@ -292,7 +318,7 @@ func Tokenize(sql string) []Token {
 		}
 
 		// Single-char operators
-		if ch == '+' || ch == '-' || ch == '*' || ch == '/' || ch == '%' || ch == '|' || ch == '&' || ch == '~' || ch == ':' {
+		if ch == '+' || ch == '-' || ch == '*' || ch == '/' || ch == '%' || ch == '|' || ch == '&' || ch == '~' || ch == ':' || ch == '^' {
 			raw := string(ch)
 			tokens = append(tokens, Token{Kind: TokOperator, Value: raw, Raw: raw})
 			i++
@@ -307,6 +333,14 @@ func Tokenize(sql string) []Token {
 			continue
 		}
 
+		// Brackets
+		if ch == '[' || ch == ']' {
+			raw := string(ch)
+			tokens = append(tokens, Token{Kind: TokBracket, Value: raw, Raw: raw})
+			i++
+			continue
+		}
+
 		// Comma
 		if ch == ',' {
 			tokens = append(tokens, Token{Kind: TokComma, Value: ",", Raw: ","})
@@ -354,33 +388,102 @@ func Tokenize(sql string) []Token {
 }
 
 // Reassemble converts tokens back into a SQL string.
+// A -- comment is always followed by a newline before further tokens, so that a
+// rewrite which drops or moves the whitespace after it can't comment out the rest
+// of the statement.
 func Reassemble(tokens []Token) string {
 	var b strings.Builder
-	for _, t := range tokens {
+	for i, t := range tokens {
 		b.WriteString(t.Raw)
+		if t.Kind == TokComment && strings.HasPrefix(t.Raw, "--") && i+1 < len(tokens) &&
+			!(tokens[i+1].Kind == TokWhitespace && strings.HasPrefix(tokens[i+1].Raw, "\n")) {
+			b.WriteByte('\n')
+		}
 	}
 	return b.String()
 }
 
-// translateTokens applies all translation passes to a token stream.
-func translateTokens(tokens []Token) []Token {
+// reassembleInline reassembles tokens for splicing into generated SQL text,
+// ending a trailing -- comment with a newline so it can't swallow what follows.
+func reassembleInline(tokens []Token) string {
+	sql := Reassemble(tokens)
+	if n := len(tokens); n > 0 && tokens[n-1].Kind == TokComment && strings.HasPrefix(tokens[n-1].Raw, "--") {
+		sql += "\n"
+	}
+	return sql
+}
+
+// translateTokens applies all translation passes to a token stream, which sc
+// scopes against the catalog.
+func translateTokens(tokens []Token, sc *scope) []Token {
 	tokens = translateExplain(tokens)
+	tokens = translateNotify(tokens)
+	tokens = translateCommentOn(tokens)
 	tokens = translateGenerateSeries(tokens)
+	tokens = translateJSONEach(tokens)
+	tokens = translateJSONSet(tokens)
 	tokens = translateSequenceDDL(tokens)
+	tokens = translateSequenceDefaults(tokens, sc)
+	tokens = translateMaterializedViews(tokens, sc)
+	tokens = translateEnumDDL(tokens, sc)
+	tokens = translateDML(tokens, sc)
+	tokens = translateIntervalWrites(tokens, sc)
 	tokens = translateInterval(tokens)
-	tokens = translateDDL(tokens)
-	tokens = translateExpressions(tokens)
-	tokens = translateFunctions(tokens)
+	tokens = translateTimestampDiff(tokens, sc)
+	tokens = translateDDL(tokens, sc)
+	tokens = translateExpressions(tokens, sc)
+	tokens = translateFunctions(tokens, sc)
 	tokens = translateNullsOrdering(tokens)
+	tokens = translateEnumOrdering(tokens, sc)
 	tokens = translateParams(tokens)
+	tokens = runCustomTranslations(tokens)
+	return tokens
+}
+
+// customTranslations holds the passes added with RegisterTranslation.
+var customTranslations = struct {
+	mu     sync.RWMutex
+	passes []func([]Token) []Token
+}{}
+
+// RegisterTranslation adds a translation pass for rewrites the built-in ones do
+// not cover. Passes run in registration order at the end of the pipeline, once
+// per statement: each receives the statement's tokens as the built-in passes
+// left them, in SQLite's dialect with $n parameters already turned into ?, and
+// returns the tokens to use instead. A pass must not repeat or drop ? tokens,
+// since parameters are bound by position. The registry is process-wide and
+// shared by all connections.
+func RegisterTranslation(pass func([]Token) []Token) {
+	customTranslations.mu.Lock()
+	customTranslations.passes = append(customTranslations.passes, pass)
+	customTranslations.mu.Unlock()
+	// Cached translations predate the pass.
+	defaultCache.reset()
+}
+
+// runCustomTranslations applies the passes added with RegisterTranslation.
+func runCustomTranslations(tokens []Token) []Token {
+	customTranslations.mu.RLock()
+	passes := customTranslations.passes
+	customTranslations.mu.RUnlock()
+	for _, pass := range passes {
+		tokens = pass(tokens)
+	}
 	return tokens
 }
 
 // Translate converts PostgreSQL SQL to SQLite-compatible SQL.
+//
+// Rewrites that depend on how a column was declared, such as timestamp
+// subtraction, need the catalog a CREATE TABLE records. Having no database,
+// Translate and TranslateMulti use one process-wide catalog, which the
+// statements they translate update; the driver uses each database's own.
 func Translate(sql string) (string, error) {
 	tokens := Tokenize(sql)
-	tokens = translateTokens(tokens)
-	return Reassemble(tokens), nil
+	if err := checkStrict(tokens); err != nil {
+		return "", err
+	}
+	return translateStandalone(tokens), nil
 }
 
 // translatedStmt holds a translated SQL statement and its parameter count.
@@ -396,10 +499,12 @@ func TranslateMulti(sql string) ([]translatedStmt, error) {
 	stmts := splitStatements(tokens)
 	result := make([]translatedStmt, 0, len(stmts))
 	for _, stmtTokens := range stmts {
+		if err := checkStrict(stmtTokens); err != nil {
+			return nil, err
+		}
 		nParams := countTokenParams(stmtTokens)
-		stmtTokens = translateTokens(stmtTokens)
 		result = append(result, translatedStmt{
-			SQL:       Reassemble(stmtTokens),
+			SQL:       translateStandalone(stmtTokens),
 			NumParams: nParams,
 		})
 	}
@@ -486,6 +591,56 @@ func translateExplain(tokens []Token) []Token {
 	return result
 }
 
+// translateNotify rewrites the LISTEN/NOTIFY statements:
+//
+//	NOTIFY jobs, 'payload' -> SELECT pg_notify('jobs', 'payload')
+//	LISTEN jobs            -> SELECT NULL WHERE 0
+//
+// Subscriptions are made with Listen, so LISTEN and UNLISTEN become statements
+// that do nothing. The channel name is folded to lower case unless quoted.
+func translateNotify(tokens []Token) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword {
+		return tokens
+	}
+	switch tokens[i].Value {
+	case "LISTEN", "UNLISTEN":
+		return Tokenize("SELECT NULL WHERE 0")
+	case "NOTIFY":
+	default:
+		return tokens
+	}
+	j := skipTrivia(tokens, i+1)
+	if j >= len(tokens) || (tokens[j].Kind != TokIdent && tokens[j].Kind != TokKeyword) {
+		return tokens
+	}
+	channel := "'" + strings.ReplaceAll(identName(tokens[j]), "'", "''") + "'"
+	payload, end := "''", j
+	if k := skipTrivia(tokens, j+1); k < len(tokens) && tokens[k].Kind == TokComma {
+		k = skipTrivia(tokens, k+1)
+		if k >= len(tokens) || tokens[k].Kind != TokString {
+			return tokens
+		}
+		payload, end = tokens[k].Raw, k
+	}
+	out := Tokenize("SELECT pg_notify(" + channel + ", " + payload + ")")
+	return append(out, tokens[end+1:]...)
+}
+
+// translateCommentOn turns COMMENT ON ... IS ... into a no-op, as SQLite has no
+// object comments. The first word is matched as an identifier rather than
+// made a keyword, since comment is a common column name.
+func translateCommentOn(tokens []Token) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokIdent || !strings.EqualFold(tokens[i].Value, "COMMENT") {
+		return tokens
+	}
+	if _, ok := peekKeyword(tokens, i+1, "ON"); !ok {
+		return tokens
+	}
+	return Tokenize("SELECT NULL WHERE 0")
+}
+
 // tryDollarQuote checks if runes[i:] starts a dollar-quoted string ($$...$$ or $tag$...$tag$).
 // Returns the opening tag (including $ delimiters), the end position, and whether it matched.
 func tryDollarQuote(runes []rune, i, n int) (tag []rune, end int, ok bool) {
@@ -49,7 +49,7 @@ func BenchmarkTranslateTokens(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for b.Loop() {
-		translateTokens(tokens)
+		translateTokens(tokens, newScope(newCatalog(), tokens))
 	}
 }
 
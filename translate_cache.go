@@ -46,6 +46,14 @@ func (c *translateCache) put(sql, translated string) {
 	c.order = append(c.order, sql)
 }
 
+// reset drops all cached translations.
+func (c *translateCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]string, c.maxSize)
+	c.order = c.order[:0]
+}
+
 // TranslateCached translates PostgreSQL SQL to SQLite SQL, using a cache
 // to avoid repeated translation of the same query.
 func TranslateCached(sql string) (string, error) {
@@ -1,13 +1,21 @@
 package pglike
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 // translateDDL handles DDL-specific translations: type mappings, SERIAL, etc.
-func translateDDL(tokens []Token) []Token {
-	tokens = translateTypes(tokens)
+func translateDDL(tokens []Token, sc *scope) []Token {
+	recordColumnTypes(tokens, sc)
+	tokens = translateBoolDefaults(tokens)
+	tokens = translateCastNumeric(tokens)
+	tokens = translateTypes(tokens, sc)
 	tokens = translateSerial(tokens)
-	tokens = translateDefaultNow(tokens)
+	tokens = translateDefaultFuncs(tokens)
+	tokens = translateAddColumnDefault(tokens, sc)
 	tokens = translateAlterTableAddColumn(tokens)
+	tokens = translateDropColumnTriggers(tokens)
 	return tokens
 }
 
@@ -49,6 +57,186 @@ func translateAlterTableAddColumn(tokens []Token) []Token {
 	return out
 }
 
+// translateAddColumnDefault emulates ALTER TABLE ... ADD COLUMN with an
+// expression default, which SQLite rejects ("Cannot add a column with
+// non-constant default"). The column is added without the default and
+// existing rows are filled in:
+//
+//	ALTER TABLE t ADD COLUMN c TEXT DEFAULT (f())
+//	-> ALTER TABLE t ADD COLUMN c TEXT; UPDATE t SET c = (f())
+//
+// The default is recorded in the catalog, and INSERTs that leave the column
+// out, or set it to DEFAULT, get it in its place, as for DEFAULT nextval('seq')
+// columns (see translateSequenceDefaults); an explicit NULL is kept. SQLite
+// can't add a NOT NULL column without a constant default, so NOT NULL is
+// dropped from the column and enforced by triggers that reject inserts and
+// updates setting it to NULL:
+//
+//	ALTER TABLE t ADD COLUMN c TEXT NOT NULL DEFAULT (f())
+//	-> ALTER TABLE t ADD COLUMN c TEXT; UPDATE t SET c = (f());
+//	   CREATE TRIGGER "_pglike_notnull_insert_t_c" BEFORE INSERT ON t FOR EACH ROW
+//	   WHEN NEW.c IS NULL BEGIN SELECT RAISE(ABORT, 'NOT NULL constraint failed: t.c'); END;
+//	   CREATE TRIGGER "_pglike_notnull_update_t_c" BEFORE UPDATE OF c ON t ...
+//
+// ADD COLUMN IF NOT EXISTS for a column the catalog
+// already has is left alone.
+func translateAddColumnDefault(tokens []Token, sc *scope) []Token {
+	i := skipTrivia(tokens, 0)
+	if _, ok := peekKeyword(tokens, i, "ALTER"); !ok {
+		return tokens
+	}
+	tbl, ok := peekKeyword(tokens, i+1, "TABLE")
+	if !ok {
+		return tokens
+	}
+	nameStart := skipTrivia(tokens, tbl+1)
+	add := nameStart
+	for add < len(tokens) && !(tokens[add].Kind == TokKeyword && tokens[add].Value == "ADD") {
+		add++
+	}
+	if add >= len(tokens) {
+		return tokens
+	}
+	table := trimTokenWhitespace(tokens[nameStart:add])
+	if len(table) == 0 {
+		return tokens
+	}
+
+	colIdx := add
+	if c, ok := peekKeyword(tokens, add+1, "COLUMN"); ok {
+		colIdx = c
+	}
+	ifNotExists := false
+	if f, ok := peekKeyword(tokens, colIdx+1, "IF"); ok {
+		if n, ok := peekKeyword(tokens, f+1, "NOT"); ok {
+			if e, ok := peekKeyword(tokens, n+1, "EXISTS"); ok {
+				colIdx, ifNotExists = e, true
+			}
+		}
+	}
+	colIdx = skipTrivia(tokens, colIdx+1)
+	if colIdx >= len(tokens) || (tokens[colIdx].Kind != TokIdent && tokens[colIdx].Kind != TokKeyword) {
+		return tokens
+	}
+	col := tokens[colIdx]
+
+	// Find DEFAULT (expr) and NOT NULL in the column definition.
+	def, defEnd := -1, -1
+	notNull, notNullEnd := -1, -1
+	for k := colIdx + 1; k < len(tokens) && tokens[k].Kind != TokSemicolon; k++ {
+		t := tokens[k]
+		if t.Kind == TokKeyword && t.Value == "NOT" {
+			if n, ok := peekKeyword(tokens, k+1, "NULL"); ok {
+				notNull, notNullEnd = k, n
+				k = n
+				continue
+			}
+		}
+		if t.Kind == TokKeyword && t.Value == "DEFAULT" && def < 0 {
+			p := skipTrivia(tokens, k+1)
+			if p >= len(tokens) || tokens[p].Kind != TokParen || tokens[p].Value != "(" {
+				return tokens
+			}
+			def, defEnd = k, skipParenGroup(tokens, p)
+			k = defEnd
+		}
+	}
+	if def < 0 {
+		return tokens
+	}
+	expr := tokens[skipTrivia(tokens, def+1) : defEnd+1]
+
+	tableName, colName := identName(table[len(table)-1]), identName(col)
+	key := tableName + "." + colName
+	if _, typed := sc.cat.columns[key]; ifNotExists && (typed || slices.Contains(sc.cat.columnLists[tableName], colName)) {
+		return tokens
+	}
+	tableSQL := Reassemble(table)
+	colSQL := col.Raw
+	exprSQL := Reassemble(expr)
+	sc.change(catalogChange{kind: catalogAddedDefault, name: key, value: exprSQL})
+
+	var out []Token
+	for k := 0; k < len(tokens); k++ {
+		switch {
+		case k == def:
+			out = trimTokenWhitespace(out)
+			k = defEnd
+		case k == notNull:
+			out = trimTokenWhitespace(out)
+			k = notNullEnd
+		default:
+			out = append(out, tokens[k])
+		}
+	}
+	out = trimTokenWhitespace(out)
+	out = append(out, Tokenize("; UPDATE "+tableSQL+" SET "+colSQL+" = "+exprSQL)...)
+	if notNull >= 0 {
+		// SQLite can't add the constraint itself; reject NULLs with triggers
+		// instead.
+		name := strings.Trim(tableSQL, `"`) + "." + strings.Trim(colSQL, `"`)
+		for _, event := range []string{"INSERT", "UPDATE"} {
+			on := event
+			if event == "UPDATE" {
+				on += " OF " + colSQL
+			}
+			out = append(out, Tokenize("; CREATE TRIGGER \"_pglike_notnull_"+strings.ToLower(event)+"_"+
+				strings.Trim(tableSQL, `"`)+"_"+strings.Trim(colSQL, `"`)+"\""+
+				" BEFORE "+on+" ON "+tableSQL+" FOR EACH ROW WHEN NEW."+colSQL+" IS NULL"+
+				" BEGIN SELECT RAISE(ABORT, 'NOT NULL constraint failed: "+name+"'); END")...)
+		}
+	}
+	return out
+}
+
+// translateDropColumnTriggers drops the triggers translateAddColumnDefault
+// created for a column before the column itself, since SQLite refuses to drop
+// a column that a trigger still references:
+//
+//	ALTER TABLE t DROP [COLUMN] c
+//	  -> DROP TRIGGER IF EXISTS "_pglike_notnull_insert_t_c";
+//	     DROP TRIGGER IF EXISTS "_pglike_notnull_update_t_c"; ALTER TABLE t DROP [COLUMN] c
+//
+// DROP TABLE needs no rewrite: SQLite drops a table's triggers with it.
+func translateDropColumnTriggers(tokens []Token) []Token {
+	i := skipTrivia(tokens, 0)
+	if _, ok := peekKeyword(tokens, i, "ALTER"); !ok {
+		return tokens
+	}
+	tbl, ok := peekKeyword(tokens, i+1, "TABLE")
+	if !ok {
+		return tokens
+	}
+	nameStart := skipTrivia(tokens, tbl+1)
+	drop := nameStart
+	for drop < len(tokens) && !(tokens[drop].Kind == TokKeyword && tokens[drop].Value == "DROP") {
+		drop++
+	}
+	if drop >= len(tokens) {
+		return tokens
+	}
+	table := strings.Trim(Reassemble(trimTokenWhitespace(tokens[nameStart:drop])), `"`)
+
+	colIdx := drop
+	if c, ok := peekKeyword(tokens, drop+1, "COLUMN"); ok {
+		colIdx = c
+	}
+	if f, ok := peekKeyword(tokens, colIdx+1, "IF"); ok {
+		if e, ok := peekKeyword(tokens, f+1, "EXISTS"); ok {
+			colIdx = e
+		}
+	}
+	colIdx = skipTrivia(tokens, colIdx+1)
+	if colIdx >= len(tokens) || (tokens[colIdx].Kind != TokIdent && tokens[colIdx].Kind != TokKeyword) {
+		return tokens
+	}
+	col := strings.Trim(tokens[colIdx].Raw, `"`)
+
+	out := Tokenize(`DROP TRIGGER IF EXISTS "_pglike_notnull_insert_` + table + "_" + col + `"; ` +
+		`DROP TRIGGER IF EXISTS "_pglike_notnull_update_` + table + "_" + col + `"; `)
+	return append(out, tokens[i:]...)
+}
+
 // isAfterAddColumn checks if the last non-whitespace tokens in out are ADD [COLUMN].
 func isAfterAddColumn(tokens []Token) bool {
 	pos := len(tokens)
@@ -183,6 +371,8 @@ var pgTypeToSQLite = map[string]string{
 	"FLOAT8":      "REAL",
 	"NUMERIC":     "TEXT",
 	"DECIMAL":     "TEXT",
+	"TSVECTOR":    "TEXT",
+	"TSQUERY":     "TEXT",
 }
 
 // MapType maps a PostgreSQL type name to its SQLite equivalent.
@@ -196,16 +386,43 @@ func MapType(pgType string) string {
 
 // translateTypes handles PG type names in DDL, replacing them with SQLite equivalents.
 // Handles multi-word types like "DOUBLE PRECISION", "CHARACTER VARYING", "TIMESTAMP WITH TIME ZONE".
-func translateTypes(tokens []Token) []Token {
+func translateTypes(tokens []Token, sc *scope) []Token {
 	var out []Token
+	tableDDL := isTableDDL(tokens)
 	for i := 0; i < len(tokens); i++ {
 		t := tokens[i]
 
+		// Arrays are stored as JSON text, whatever their element type.
+		if (isTypeKeyword(t) || t.Kind == TokIdent && tableDDL) && isTypePosition(out) {
+			if end := arrayTypeEnd(tokens, i); end >= 0 {
+				out = append(out, Token{Kind: TokKeyword, Value: "TEXT", Raw: "TEXT"})
+				i = end
+				continue
+			}
+		}
+		if t.Kind == TokIdent && tableDDL {
+			if typ, ok := enumColumnType(out, t, sc); ok {
+				out = append(out, typ...)
+				continue
+			}
+		}
 		if t.Kind != TokKeyword {
 			out = append(out, t)
 			continue
 		}
 
+		// Leave cast targets handled by a cast function, and numeric casts
+		// whose (p,s) modifier it needs, for translateCast.
+		if _, ok := castFuncs[t.Value]; (ok || t.Value == "NUMERIC" || t.Value == "DECIMAL") && isCastTarget(out) {
+			out = append(out, t)
+			continue
+		}
+		// A type keyword used as a column name (timestamp, date, ...) is left alone.
+		if !isTypePosition(out) {
+			out = append(out, t)
+			continue
+		}
+
 		switch t.Value {
 		case "DOUBLE":
 			// DOUBLE PRECISION -> REAL
@@ -272,9 +489,21 @@ func translateTypes(tokens []Token) []Token {
 			out = append(out, Token{Kind: TokKeyword, Value: "TEXT", Raw: "TEXT"})
 			continue
 
+		case "CITEXT":
+			// CITEXT column -> TEXT COLLATE pg_citext, so that =, UNIQUE and
+			// ORDER BY ignore case as PG's citext does and can still use
+			// indexes; casts to citext become plain TEXT.
+			if j := prevSignificant(out, len(out)); j >= 0 && out[j].Kind == TokIdent {
+				out = append(out, Tokenize("TEXT COLLATE pg_citext")...)
+			} else {
+				out = append(out, Token{Kind: TokKeyword, Value: "TEXT", Raw: "TEXT"})
+			}
+			continue
+
 		case "INTERVAL":
-			// INTERVAL -> TEXT (column type only; arithmetic INTERVAL handled by translateInterval)
-			out = append(out, Token{Kind: TokKeyword, Value: "TEXT", Raw: "TEXT"})
+			// INTERVAL -> REAL, as intervals are held in seconds (column type
+			// only; arithmetic INTERVAL handled by translateInterval)
+			out = append(out, Token{Kind: TokKeyword, Value: "REAL", Raw: "REAL"})
 			continue
 
 		case "TIME":
@@ -314,6 +543,64 @@ func translateTypes(tokens []Token) []Token {
 	return out
 }
 
+// isTypeKeyword reports whether t is a keyword that names a type, so that an
+// ARRAY constructor after another keyword (SELECT ARRAY[1, 2]) is not taken
+// for an array type.
+func isTypeKeyword(t Token) bool {
+	if t.Kind != TokKeyword {
+		return false
+	}
+	if _, ok := pgTypeToSQLite[t.Value]; ok {
+		return true
+	}
+	if _, ok := castFuncs[t.Value]; ok {
+		return true
+	}
+	switch t.Value {
+	case "TEXT", "INTEGER", "INT", "DOUBLE", "BLOB", "CITEXT", "SERIAL", "BIGSERIAL", "SMALLSERIAL":
+		return true
+	}
+	return false
+}
+
+// arrayTypeEnd returns the index of the last token of an array type starting
+// at tokens[start] (INTEGER[], TEXT[][], VARCHAR(10)[3], INTEGER ARRAY), or -1
+// when the type there is not an array.
+func arrayTypeEnd(tokens []Token, start int) int {
+	j := skipTrivia(tokens, start+1)
+	for j < len(tokens) {
+		if t := tokens[j]; t.Kind == TokKeyword {
+			switch t.Value {
+			case "PRECISION", "VARYING", "WITH", "WITHOUT", "TIME", "ZONE":
+				j = skipTrivia(tokens, j+1)
+				continue
+			}
+		}
+		break
+	}
+	if j < len(tokens) && tokens[j].Kind == TokParen && tokens[j].Value == "(" {
+		j = skipTrivia(tokens, skipParenGroup(tokens, j)+1)
+	}
+	end := -1
+	if j < len(tokens) && tokens[j].Kind == TokKeyword && tokens[j].Value == "ARRAY" {
+		end = j
+		j = skipTrivia(tokens, j+1)
+	}
+	// One or more [] or [n].
+	for j < len(tokens) && tokens[j].Kind == TokBracket && tokens[j].Value == "[" {
+		k := skipTrivia(tokens, j+1)
+		if k < len(tokens) && tokens[k].Kind == TokNumber {
+			k = skipTrivia(tokens, k+1)
+		}
+		if k >= len(tokens) || tokens[k].Kind != TokBracket || tokens[k].Value != "]" {
+			break
+		}
+		end = k
+		j = skipTrivia(tokens, k+1)
+	}
+	return end
+}
+
 // peekKeyword looks past whitespace for an expected keyword, returning the index and true if found.
 func peekKeyword(tokens []Token, start int, keyword string) (int, bool) {
 	j := start
@@ -326,6 +613,80 @@ func peekKeyword(tokens []Token, start int, keyword string) (int, bool) {
 	return start, false
 }
 
+// isTableDDL reports whether tokens are a CREATE TABLE or ALTER TABLE statement.
+func isTableDDL(tokens []Token) bool {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword || (tokens[i].Value != "CREATE" && tokens[i].Value != "ALTER") {
+		return false
+	}
+	for j := i + 1; j < len(tokens); j++ {
+		if tokens[j].Kind == TokKeyword && tokens[j].Value == "TABLE" {
+			return true
+		}
+		if tokens[j].Kind != TokWhitespace && tokens[j].Kind != TokKeyword {
+			return false
+		}
+	}
+	return false
+}
+
+// isTypePosition reports whether a type name may follow out: after ::, AS
+// (CAST, CREATE SEQUENCE), TYPE (ALTER COLUMN) or a column name in a definition.
+// A keyword counts as a column name when it follows (, a comma, ADD, COLUMN or
+// IF NOT EXISTS, as in CREATE TABLE t (timestamp TIMESTAMP); a type keyword in
+// any other position, such as SELECT timestamp FROM t, is a column reference.
+func isTypePosition(out []Token) bool {
+	j := prevSignificant(out, len(out))
+	if j < 0 {
+		return true
+	}
+	p := out[j]
+	switch p.Kind {
+	case TokIdent:
+		return true
+	case TokOperator:
+		return p.Value == "::"
+	case TokKeyword:
+		switch p.Value {
+		case "AS", "TYPE":
+			return true
+		case "ADD", "COLUMN", "EXISTS":
+			return false
+		}
+		return isColumnNamePosition(out, j)
+	}
+	return false
+}
+
+// isColumnNamePosition reports whether the token at i starts a column definition.
+func isColumnNamePosition(out []Token, i int) bool {
+	k := prevSignificant(out, i)
+	if k < 0 {
+		return false
+	}
+	p := out[k]
+	return p.Kind == TokComma || (p.Kind == TokParen && p.Value == "(") ||
+		(p.Kind == TokKeyword && (p.Value == "ADD" || p.Value == "COLUMN" || p.Value == "EXISTS"))
+}
+
+// prevSignificant returns the index of the last non-whitespace token before i, or -1.
+func prevSignificant(tokens []Token, i int) int {
+	j := i - 1
+	for j >= 0 && tokens[j].Kind == TokWhitespace {
+		j--
+	}
+	return j
+}
+
+// isCastTarget reports whether the next token follows a :: cast operator.
+func isCastTarget(out []Token) bool {
+	j := len(out) - 1
+	for j >= 0 && out[j].Kind == TokWhitespace {
+		j--
+	}
+	return j >= 0 && out[j].Kind == TokOperator && out[j].Value == "::"
+}
+
 // skipParenGroup skips past whitespace and a parenthesized group like (100) or (10,2).
 // Returns the index of the last token consumed (the closing paren), or start-1 if no paren found.
 func skipParenGroup(tokens []Token, start int) int {
@@ -349,10 +710,73 @@ func skipParenGroup(tokens []Token, start int) int {
 	return start - 1 // no paren, don't skip anything
 }
 
-// translateDefaultNow converts DEFAULT NOW() and DEFAULT CURRENT_TIMESTAMP/CURRENT_DATE/CURRENT_TIME
-// to DEFAULT (datetime('now')), DEFAULT (date('now')), or DEFAULT (time('now')).
+// pgBoolStrings maps the string forms PG accepts for a boolean to its value.
+var pgBoolStrings = map[string]bool{
+	"t": true, "true": true, "y": true, "yes": true, "on": true, "1": true,
+	"f": false, "false": false, "n": false, "no": false, "off": false, "0": false,
+}
+
+// translateBoolDefaults converts string defaults of BOOLEAN columns to 1 or 0,
+// as translateBooleans does for TRUE and FALSE: DEFAULT 't' -> DEFAULT 1,
+// DEFAULT 'false'::boolean -> DEFAULT 0. SQLite would otherwise store the
+// string itself. Strings PG would reject are left alone.
+func translateBoolDefaults(tokens []Token) []Token {
+	if !isTableDDL(tokens) {
+		return tokens
+	}
+	var out []Token
+	boolCol, depth := false, 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Kind == TokKeyword && (t.Value == "BOOLEAN" || t.Value == "BOOL"):
+			if j := prevSignificant(tokens, i); j >= 0 && (tokens[j].Kind == TokIdent ||
+				tokens[j].Kind == TokKeyword && isColumnNamePosition(tokens, j)) {
+				boolCol, depth = true, 0
+			}
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			if depth == 0 {
+				boolCol = false
+			}
+			depth--
+		case t.Kind == TokComma && depth == 0:
+			boolCol = false
+		case t.Kind == TokKeyword && t.Value == "DEFAULT" && boolCol && depth == 0:
+			k := skipTrivia(tokens, i+1)
+			if k >= len(tokens) || tokens[k].Kind != TokString || !strings.HasPrefix(tokens[k].Value, "'") {
+				break
+			}
+			v, ok := pgBoolStrings[strings.ToLower(strings.TrimSpace(strings.Trim(tokens[k].Value, "'")))]
+			if !ok {
+				break
+			}
+			out = append(out, tokens[i:k]...)
+			n := "0"
+			if v {
+				n = "1"
+			}
+			out = append(out, Token{Kind: TokNumber, Value: n, Raw: n})
+			i = k
+			// A ::boolean cast on the literal is now redundant.
+			if c := skipTrivia(tokens, k+1); c < len(tokens) && tokens[c].Kind == TokOperator && tokens[c].Value == "::" {
+				if b := skipTrivia(tokens, c+1); b < len(tokens) && tokens[b].Kind == TokKeyword && (tokens[b].Value == "BOOLEAN" || tokens[b].Value == "BOOL") {
+					i = b
+				}
+			}
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// translateDefaultFuncs converts DEFAULT NOW() and DEFAULT CURRENT_TIMESTAMP/CURRENT_DATE/CURRENT_TIME
+// to DEFAULT (datetime('now')), DEFAULT (date('now')), or DEFAULT (time('now')),
+// and wraps any other function call default: DEFAULT gen_random_uuid() -> DEFAULT (gen_random_uuid()).
 // SQLite requires function calls in DEFAULT clauses to be wrapped in parentheses.
-func translateDefaultNow(tokens []Token) []Token {
+func translateDefaultFuncs(tokens []Token) []Token {
 	// Map of CURRENT_* keywords to their SQLite function equivalents.
 	currentFuncMap := map[string]string{
 		"CURRENT_TIMESTAMP": "datetime",
@@ -416,7 +840,20 @@ func translateDefaultNow(tokens []Token) []Token {
 				}
 			}
 
-			// Not a recognized datetime default, just pass through DEFAULT
+			// Any other function call: wrap it in parentheses.
+			if j < len(tokens) && tokens[j].Kind == TokIdent && isFuncCall(tokens, j) {
+				end := skipParenGroup(tokens, j+1)
+				out = append(out,
+					Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+					Token{Kind: TokParen, Value: "(", Raw: "("},
+				)
+				out = append(out, tokens[j:end+1]...)
+				out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+				i = end
+				continue
+			}
+
+			// Not a function default, just pass through DEFAULT
 			continue
 		}
 		out = append(out, t)
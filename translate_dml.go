@@ -0,0 +1,1254 @@
+package pglike
+
+import (
+	"slices"
+	"strings"
+)
+
+// translateDML handles DML-specific rewrites (UPDATE, DELETE, INSERT forms).
+func translateDML(tokens []Token, sc *scope) []Token {
+	tokens = translateReturningNames(tokens)
+	tokens = translateMultiColumnSet(tokens)
+	tokens = translateSelectInto(tokens)
+	tokens = translateDeleteUsing(tokens)
+	tokens = translateUpdateAlias(tokens)
+	tokens = translateInsertDefaults(tokens, sc)
+	tokens = translateLockingClauses(tokens)
+	tokens = translateTableSample(tokens)
+	tokens = translateLateral(tokens)
+	return tokens
+}
+
+// translateLateral drops the LATERAL keyword from joins:
+//
+//	SELECT * FROM a LEFT JOIN LATERAL (SELECT ...) x ON true -> SELECT * FROM a LEFT JOIN (SELECT ...) x ON true
+//	SELECT * FROM a, LATERAL json_each(a.tags)              -> SELECT * FROM a, json_each(a.tags)
+//
+// SQLite joins a subquery that does not refer to the tables before it, and a
+// table-valued function that does, the same way PG does. A subquery that does
+// refer to them cannot be evaluated per row in SQLite, so it fails with "no
+// such column" rather than returning different rows, unless it is a LEFT JOIN
+// LATERAL of at most one row, which lateralScalar turns into scalar subqueries.
+func translateLateral(tokens []Token) []Token {
+	for {
+		rewritten, ok := lateralScalar(tokens)
+		if !ok {
+			break
+		}
+		tokens = rewritten
+	}
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == TokKeyword && tokens[i].Value == "LATERAL" {
+			if p := prevSignificant(tokens, i); p >= 0 && (tokens[p].Value == "JOIN" || tokens[p].Kind == TokComma) {
+				// Drop LATERAL and the whitespace after it.
+				i = skipTrivia(tokens, i+1) - 1
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// lateralScalar rewrites the first LEFT JOIN LATERAL whose subquery ends in
+// LIMIT 1 and is joined ON true, replacing each alias.col reference with a
+// scalar subquery for that column, which SQLite evaluates per row and which
+// gives NULL when there is no row, as the outer join does:
+//
+//	SELECT u.name, o.total FROM users u LEFT JOIN LATERAL (SELECT total FROM orders WHERE uid = u.id ORDER BY at DESC LIMIT 1) o ON true
+//	-> SELECT u.name, (SELECT total FROM orders WHERE uid = u.id ORDER BY at DESC LIMIT 1) FROM users u
+//
+// Each column runs the subquery again, so one with volatile functions or
+// parameters, or an alias used other than as alias.col, is left alone, as is
+// a join whose columns a * or alias.* in the select list takes in, or whose ON
+// condition is more than true.
+func lateralScalar(tokens []Token) ([]Token, bool) {
+	for i := range tokens {
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "LATERAL" {
+			continue
+		}
+		join := prevSignificant(tokens, i)
+		if join < 0 || tokens[join].Value != "JOIN" {
+			continue
+		}
+		start := prevSignificant(tokens, join)
+		if start >= 0 && tokens[start].Value == "OUTER" {
+			start = prevSignificant(tokens, start)
+		}
+		if start < 0 || tokens[start].Value != "LEFT" {
+			continue
+		}
+		open := skipTrivia(tokens, i+1)
+		if open >= len(tokens) || tokens[open].Kind != TokParen || tokens[open].Value != "(" {
+			continue
+		}
+		close := skipParenGroup(tokens, open)
+		sub := trimTokenWhitespace(tokens[open+1 : close])
+		alias := collectAlias(tokens, close+1)
+		if len(sub) == 0 || sub[0].Value != "SELECT" || len(alias) == 0 || !limitsToOneRow(sub) {
+			continue
+		}
+		on := skipTrivia(tokens, close+1+len(alias))
+		cond := skipTrivia(tokens, on+1)
+		if cond >= len(tokens) || tokens[on].Value != "ON" || tokens[cond].Value != "TRUE" {
+			continue
+		}
+		if n := skipTrivia(tokens, cond+1); n < len(tokens) && !endsJoinCondition(tokens[n]) {
+			continue // ON true AND ...
+		}
+		cols, ok := lateralColumns(sub)
+		if !ok || selectsStar(tokens) {
+			continue
+		}
+
+		name := strings.ToLower(alias[len(alias)-1].Value)
+		var out []Token
+		depth, selectList := 0, false
+		for j := 0; j < len(tokens); j++ {
+			switch t := tokens[j]; {
+			case t.Kind == TokParen && t.Value == "(":
+				depth++
+			case t.Kind == TokParen && t.Value == ")":
+				depth--
+			case depth == 0 && t.Kind == TokKeyword && (t.Value == "SELECT" || t.Value == "FROM"):
+				selectList = t.Value == "SELECT"
+			}
+			switch {
+			case j == start:
+				// Drop the join and the whitespace before it.
+				for len(out) > 0 && out[len(out)-1].Kind == TokWhitespace {
+					out = out[:len(out)-1]
+				}
+				j = cond
+				continue
+			case tokens[j].Kind != TokIdent || strings.ToLower(tokens[j].Value) != name:
+				out = append(out, tokens[j])
+				continue
+			}
+			if j+2 >= len(tokens) || tokens[j+1].Kind != TokDot {
+				return nil, false
+			}
+			col, ok := cols[identName(tokens[j+2])]
+			if !ok {
+				return nil, false
+			}
+			out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("},
+				Token{Kind: TokKeyword, Value: "SELECT", Raw: "SELECT"},
+				Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+			out = append(out, col...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+			// A whole select-list item keeps the column's name, as in PG.
+			if p := prevSignificant(tokens, j); selectList && depth == 0 && p >= 0 && (tokens[p].Kind == TokComma || tokens[p].Value == "SELECT") {
+				if n := skipTrivia(tokens, j+3); n == len(tokens) || tokens[n].Kind == TokComma || tokens[n].Value == "FROM" {
+					out = append(out, Tokenize(" AS "+tokens[j+2].Raw)...)
+				}
+			}
+			j += 2
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// endsJoinCondition reports whether t, following a join's ON condition, ends
+// it.
+func endsJoinCondition(t Token) bool {
+	switch t.Kind {
+	case TokSemicolon, TokComma:
+		return true
+	case TokParen:
+		return t.Value == ")"
+	case TokKeyword:
+		switch t.Value {
+		case "WHERE", "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT",
+			"JOIN", "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+			return true
+		}
+	}
+	return false
+}
+
+// selectsStar reports whether the top-level select list of tokens has a *
+// item, which takes in the columns of every table in the FROM clause.
+func selectsStar(tokens []Token) bool {
+	depth, selectList := 0, false
+	for j, t := range tokens {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && (t.Value == "SELECT" || t.Value == "FROM"):
+			selectList = t.Value == "SELECT"
+		case depth == 0 && selectList && t.Kind == TokOperator && t.Value == "*":
+			if p := prevSignificant(tokens, j); p >= 0 && (tokens[p].Kind == TokComma || tokens[p].Kind == TokKeyword) {
+				return true // * rather than table.*
+			}
+		}
+	}
+	return false
+}
+
+// limitsToOneRow reports whether the SELECT sub ends in LIMIT 1 at the top level.
+func limitsToOneRow(sub []Token) bool {
+	depth := 0
+	for k, t := range sub {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "LIMIT":
+			n := skipTrivia(sub, k+1)
+			return n == len(sub)-1 && sub[n].Value == "1"
+		}
+	}
+	return false
+}
+
+// lateralColumns maps the output column names of the SELECT sub to the body
+// of a scalar subquery giving that column: "total FROM orders ... LIMIT 1".
+// It fails for a select list with * or volatile functions.
+func lateralColumns(sub []Token) (map[string][]Token, bool) {
+	if hasVolatileCall(sub) || countTokenParams(sub) > 0 {
+		return nil, false // repeating it would repeat its parameters too
+	}
+	fromIdx, depth := len(sub), 0
+	for k := 1; k < len(sub); k++ {
+		if t := sub[k]; t.Kind == TokParen && t.Value == "(" {
+			depth++
+		} else if t.Kind == TokParen && t.Value == ")" {
+			depth--
+		} else if depth == 0 && t.Kind == TokKeyword && t.Value == "FROM" {
+			fromIdx = k
+			break
+		}
+	}
+	cols := map[string][]Token{}
+	for _, item := range splitTopLevel(sub[1:fromIdx]) {
+		item = trimTokenWhitespace(item)
+		if len(item) == 0 || item[len(item)-1].Kind == TokOperator && item[len(item)-1].Value == "*" {
+			return nil, false
+		}
+		last := item[len(item)-1]
+		if last.Kind != TokIdent {
+			continue // PG names it after a function or ?column?; rarely referenced
+		}
+		if p := prevSignificant(item, len(item)-1); p >= 0 && item[p].Kind == TokKeyword && item[p].Value == "AS" {
+			item = trimTokenWhitespace(item[:p])
+		}
+		body := append([]Token(nil), item...)
+		body = append(body, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+		cols[identName(last)] = append(body, sub[fromIdx:]...)
+	}
+	return cols, true
+}
+
+// translateTableSample strips TABLESAMPLE clauses, so the query reads the
+// whole table rather than a sample of it:
+//
+//	SELECT * FROM t TABLESAMPLE BERNOULLI (10) REPEATABLE (42) -> SELECT * FROM t
+//
+// A sample is a random subset, so all rows is a valid (if unlikely) result.
+// Strict mode rejects TABLESAMPLE instead.
+func translateTableSample(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == TokKeyword && tokens[i].Value == "TABLESAMPLE" {
+			if end, ok := tableSampleEnd(tokens, i); ok {
+				for len(out) > 0 && out[len(out)-1].Kind == TokWhitespace {
+					out = out[:len(out)-1]
+				}
+				i = end
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// tableSampleEnd returns the index of the last token of the TABLESAMPLE
+// method (args) [REPEATABLE (seed)] clause starting at tokens[i].
+func tableSampleEnd(tokens []Token, i int) (int, bool) {
+	j := skipTrivia(tokens, i+1)
+	if j >= len(tokens) || (tokens[j].Kind != TokKeyword && tokens[j].Kind != TokIdent) {
+		return i, false
+	}
+	end := skipParenGroup(tokens, j+1)
+	if end == j {
+		return i, false // no (args)
+	}
+	if k, ok := peekKeyword(tokens, end+1, "REPEATABLE"); ok {
+		if seedEnd := skipParenGroup(tokens, k+1); seedEnd > k {
+			end = seedEnd
+		}
+	}
+	return end, true
+}
+
+// translateLockingClauses strips PG's row-locking clauses, which SQLite has no
+// syntax for:
+//
+//	SELECT * FROM jobs WHERE id = ? FOR UPDATE SKIP LOCKED -> SELECT * FROM jobs WHERE id = ?
+//
+// FOR UPDATE, FOR NO KEY UPDATE, FOR SHARE and FOR KEY SHARE are removed along
+// with OF table lists and NOWAIT / SKIP LOCKED. SQLite locks the whole database
+// for writing instead, so a writing transaction never sees a row change under it.
+func translateLockingClauses(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == TokKeyword && tokens[i].Value == "FOR" {
+			if end, ok := lockingClauseEnd(tokens, i); ok {
+				for len(out) > 0 && out[len(out)-1].Kind == TokWhitespace {
+					out = out[:len(out)-1]
+				}
+				i = end
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// lockingClauseEnd returns the index of the last token of the locking clause
+// starting with the FOR at tokens[i], if it is one.
+func lockingClauseEnd(tokens []Token, i int) (int, bool) {
+	// word returns the index of the next token if it is the given word.
+	word := func(at int, w string) (int, bool) {
+		j := skipTrivia(tokens, at+1)
+		if j < len(tokens) && (tokens[j].Kind == TokKeyword || tokens[j].Kind == TokIdent) && strings.EqualFold(tokens[j].Value, w) {
+			return j, true
+		}
+		return at, false
+	}
+
+	end, ok := word(i, "UPDATE")
+	if !ok {
+		end, ok = word(i, "SHARE")
+	}
+	if !ok {
+		if j, isNo := word(i, "NO"); isNo {
+			if j, ok = word(j, "KEY"); ok {
+				end, ok = word(j, "UPDATE")
+			}
+		} else if j, isKey := word(i, "KEY"); isKey {
+			end, ok = word(j, "SHARE")
+		}
+	}
+	if !ok {
+		return i, false
+	}
+
+	if j, ok := word(end, "OF"); ok {
+		// OF table [, table ...]
+		for {
+			k := skipTrivia(tokens, j+1)
+			for k < len(tokens) && (tokens[k].Kind == TokIdent || tokens[k].Kind == TokDot) {
+				j = k
+				k++
+			}
+			k = skipTrivia(tokens, j+1)
+			if k >= len(tokens) || tokens[k].Kind != TokComma {
+				break
+			}
+			j = k
+		}
+		end = j
+	}
+	if j, ok := word(end, "NOWAIT"); ok {
+		end = j
+	} else if j, ok := word(end, "SKIP"); ok {
+		if j, ok = word(j, "LOCKED"); ok {
+			end = j
+		}
+	}
+	return end, true
+}
+
+// parseInsertValues parses an INSERT INTO t (cols) VALUES (...), (...)
+// statement, returning the index of the column list's open paren, the columns,
+// the rows of values, and the index of the last row's close paren. ok is false
+// for other statements, including INSERT without a column list or with a row
+// that has a different number of values.
+func parseInsertValues(tokens []Token) (open int, cols [][]Token, rows [][][]Token, end int, ok bool) {
+	start := skipTrivia(tokens, 0)
+	if start >= len(tokens) || tokens[start].Kind != TokKeyword || tokens[start].Value != "INSERT" {
+		return 0, nil, nil, 0, false
+	}
+	open = start
+	for open < len(tokens) && !(tokens[open].Kind == TokParen && tokens[open].Value == "(") {
+		if tokens[open].Kind == TokKeyword && tokens[open].Value == "VALUES" || tokens[open].Kind == TokSemicolon {
+			return 0, nil, nil, 0, false
+		}
+		open++
+	}
+	if open >= len(tokens) {
+		return 0, nil, nil, 0, false
+	}
+	cols, closeCols := parseFuncArgs(tokens, open)
+	values, ok := peekKeyword(tokens, closeCols+1, "VALUES")
+	if !ok {
+		return 0, nil, nil, 0, false
+	}
+
+	// Parse the VALUES rows.
+	end = values
+	for {
+		p := skipTrivia(tokens, end+1)
+		if p >= len(tokens) || tokens[p].Kind != TokParen || tokens[p].Value != "(" {
+			return 0, nil, nil, 0, false
+		}
+		row, closeRow := parseFuncArgs(tokens, p)
+		if len(row) != len(cols) {
+			return 0, nil, nil, 0, false
+		}
+		rows = append(rows, row)
+		end = closeRow
+		next := skipTrivia(tokens, end+1)
+		if next >= len(tokens) || tokens[next].Kind != TokComma {
+			break
+		}
+		end = next
+	}
+	return open, cols, rows, end, true
+}
+
+// insertValuesList formats the column list and VALUES rows of an INSERT:
+// (a, b) VALUES (1, 2), (3, 4).
+func insertValuesList(cols [][]Token, rows [][][]Token) []Token {
+	comma := []Token{{Kind: TokComma, Value: ",", Raw: ","}, {Kind: TokWhitespace, Value: " ", Raw: " "}}
+	list := func(items [][]Token) []Token {
+		l := []Token{{Kind: TokParen, Value: "(", Raw: "("}}
+		for n, item := range items {
+			if n > 0 {
+				l = append(l, comma...)
+			}
+			l = append(l, trimTokenWhitespace(item)...)
+		}
+		return append(l, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	}
+	out := list(cols)
+	out = append(out, Tokenize(" VALUES ")...)
+	for r, row := range rows {
+		if r > 0 {
+			out = append(out, comma...)
+		}
+		out = append(out, list(row)...)
+	}
+	return out
+}
+
+// insertTarget returns the name of the table INSERT INTO inserts into, and
+// the index just past it.
+func insertTarget(tokens []Token) (string, int, bool) {
+	insert, ok := peekKeyword(tokens, skipTrivia(tokens, 0), "INSERT")
+	if !ok {
+		return "", 0, false
+	}
+	into, ok := peekKeyword(tokens, insert+1, "INTO")
+	if !ok {
+		return "", 0, false
+	}
+	table := ""
+	j := skipTrivia(tokens, into+1)
+	for ; j < len(tokens) && (tokens[j].Kind == TokIdent || tokens[j].Kind == TokDot); j++ {
+		if tokens[j].Kind == TokIdent {
+			table = identName(tokens[j])
+		}
+	}
+	return table, j, table != ""
+}
+
+// translateInsertDefaults removes DEFAULT items from INSERT ... VALUES lists,
+// which SQLite doesn't accept, by leaving those columns out of the insert:
+//
+//	INSERT INTO t (a, b) VALUES (DEFAULT, ?) -> INSERT INTO t (b) VALUES (?)
+//	INSERT INTO t (a) VALUES (DEFAULT)       -> INSERT INTO t DEFAULT VALUES
+//
+// A column that is DEFAULT in some rows but not others can't be left out, so
+// its DEFAULT items become the expression the column was declared DEFAULT, or
+// NULL if it was declared without one:
+//
+//	INSERT INTO t (a, b) VALUES (DEFAULT, 'x'), (5, 'y') -> INSERT INTO t (a, b) VALUES ((0), 'x'), (5, 'y')
+//
+// An INSERT without a column list is given the columns its values are for
+// first, as in translateSequenceDefaults. Both need the catalog to know the
+// table's columns, so the table must have been created through pglike; other
+// statements are left unchanged. INSERT INTO t DEFAULT VALUES itself is valid
+// SQLite and passes through.
+func translateInsertDefaults(tokens []Token, sc *scope) []Token {
+	isDefault := func(v []Token) bool {
+		v = trimTokenWhitespace(v)
+		return len(v) == 1 && v[0].Kind == TokKeyword && v[0].Value == "DEFAULT"
+	}
+	table, j, ok := insertTarget(tokens)
+	if !ok {
+		return tokens
+	}
+	if slices.ContainsFunc(tokens[j:], func(t Token) bool { return isDefault([]Token{t}) }) {
+		tokens = insertColumnList(tokens, j, table, sc)
+	}
+	open, cols, rows, end, ok := parseInsertValues(tokens)
+	if !ok {
+		return tokens
+	}
+
+	var keep []int
+	changed := false
+	for c, col := range cols {
+		defaults := 0
+		for _, row := range rows {
+			if isDefault(row[c]) {
+				defaults++
+			}
+		}
+		switch defaults {
+		case 0:
+			keep = append(keep, c)
+			continue
+		case len(rows):
+			changed = true
+			continue
+		}
+		col = trimTokenWhitespace(col)
+		if _, known := sc.cat.columnLists[table]; !known || len(col) != 1 {
+			return tokens
+		}
+		value := []Token{{Kind: TokKeyword, Value: "NULL", Raw: "NULL"}}
+		if expr, ok := sc.cat.declaredDefaults[table+"."+identName(col[0])]; ok {
+			value = Tokenize("(" + expr + ")")
+		}
+		for _, row := range rows {
+			if isDefault(row[c]) {
+				row[c] = value
+			}
+		}
+		keep = append(keep, c)
+		changed = true
+	}
+	if !changed || (len(keep) == 0 && len(rows) > 1) {
+		return tokens
+	}
+
+	var out []Token
+	out = append(out, trimTokenWhitespace(tokens[:open])...)
+	if len(keep) == 0 {
+		out = append(out, Tokenize(" DEFAULT VALUES")...)
+		return append(out, tokens[end+1:]...)
+	}
+	pick := func(items [][]Token) [][]Token {
+		var kept [][]Token
+		for _, c := range keep {
+			kept = append(kept, items[c])
+		}
+		return kept
+	}
+	var kept [][][]Token
+	for _, row := range rows {
+		kept = append(kept, pick(row))
+	}
+	out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+	out = append(out, insertValuesList(pick(cols), kept)...)
+	return append(out, tokens[end+1:]...)
+}
+
+// translateSelectInto rewrites PG's table-creating SELECT INTO into CREATE TABLE AS:
+//
+//	SELECT a, b INTO [TEMP|TEMPORARY|UNLOGGED] [TABLE] t FROM s -> CREATE [TEMP] TABLE t AS SELECT a, b FROM s
+//
+// Only a statement-level SELECT is rewritten; INSERT INTO is never affected.
+func translateSelectInto(tokens []Token) []Token {
+	start := 0
+	for start < len(tokens) && (tokens[start].Kind == TokWhitespace || tokens[start].Kind == TokComment) {
+		start++
+	}
+	if start >= len(tokens) || tokens[start].Kind != TokKeyword || tokens[start].Value != "SELECT" {
+		return tokens
+	}
+
+	// INTO must appear at depth 0 before FROM.
+	into := -1
+	depth := 0
+	for i := start + 1; i < len(tokens) && into < 0; i++ {
+		t := tokens[i]
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "INTO":
+			into = i
+		case depth == 0 && (t.Kind == TokSemicolon || (t.Kind == TokKeyword && t.Value == "FROM")):
+			return tokens
+		}
+	}
+	if into < 0 {
+		return tokens
+	}
+
+	// INTO [TEMP|TEMPORARY|UNLOGGED] [TABLE] name
+	j := into + 1
+	temp := false
+	var name []Token
+	for ; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.Kind == TokWhitespace {
+			if len(name) > 0 {
+				break
+			}
+			continue
+		}
+		if len(name) == 0 && t.Kind == TokKeyword {
+			switch t.Value {
+			case "TEMP", "TEMPORARY":
+				temp = true
+				continue
+			case "UNLOGGED", "TABLE":
+				continue
+			}
+		}
+		if t.Kind != TokIdent && t.Kind != TokKeyword && t.Kind != TokDot {
+			break
+		}
+		name = append(name, t)
+	}
+	if len(name) == 0 {
+		return tokens
+	}
+
+	create := "CREATE TABLE "
+	if temp {
+		create = "CREATE TEMP TABLE "
+	}
+	var out []Token
+	out = append(out, tokens[:start]...)
+	out = append(out, Tokenize(create)...)
+	out = append(out, name...)
+	out = append(out, Tokenize(" AS ")...)
+	out = append(out, trimTokenWhitespace(tokens[start:into])...)
+	out = append(out, tokens[j:]...)
+	return out
+}
+
+// translateDeleteUsing rewrites PG's multi-table DELETE, which SQLite lacks,
+// into a DELETE of the target rows the join selects:
+//
+//	DELETE FROM [ONLY] a [[AS] x] USING b, c WHERE cond [RETURNING ...]
+//	  -> DELETE FROM a [AS x] WHERE rowid IN (SELECT x.rowid FROM a [AS x], b, c WHERE cond) [RETURNING ...]
+//
+// The target is matched by rowid, so it must not be a WITHOUT ROWID table.
+// RETURNING can only refer to the target's columns, and loses the target's
+// qualifier, which SQLite does not accept there. SQLite (3.33 and later)
+// has UPDATE ... FROM; see translateUpdateAlias for the one change it needs.
+func translateDeleteUsing(tokens []Token) []Token {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "DELETE" && isStatementVerb(tokens, i):
+			if out, ok := rewriteDeleteUsing(tokens, i); ok {
+				return out
+			}
+			return tokens
+		}
+	}
+	return tokens
+}
+
+// isStatementVerb reports whether the keyword at tokens[i] starts the
+// statement, alone or after a WITH clause, rather than being part of ON DELETE,
+// FOR UPDATE or DO UPDATE.
+func isStatementVerb(tokens []Token, i int) bool {
+	p := skipTriviaBack(tokens, i) - 1
+	return p < 0 || tokens[p].Kind == TokSemicolon || tokens[p].Kind == TokParen && tokens[p].Value == ")"
+}
+
+func rewriteDeleteUsing(tokens []Token, del int) ([]Token, bool) {
+	j, ok := peekKeyword(tokens, del+1, "FROM")
+	if !ok {
+		return nil, false
+	}
+	if k, ok := peekKeyword(tokens, j+1, "ONLY"); ok {
+		j = k
+	}
+	nameStart := skipTrivia(tokens, j+1)
+	nameEnd := nameStart
+	for nameEnd < len(tokens) && (tokens[nameEnd].Kind == TokIdent || tokens[nameEnd].Kind == TokDot ||
+		(tokens[nameEnd].Kind == TokKeyword && tokens[nameEnd].Value != "USING")) {
+		nameEnd++
+	}
+	if nameEnd == nameStart {
+		return nil, false
+	}
+	name := tokens[nameStart:nameEnd]
+
+	// Optional alias: [AS] x
+	ref := name
+	var alias []Token
+	k := skipTrivia(tokens, nameEnd)
+	if as, ok := peekKeyword(tokens, nameEnd, "AS"); ok {
+		k = skipTrivia(tokens, as+1)
+		if k >= len(tokens) || (tokens[k].Kind != TokIdent && tokens[k].Kind != TokKeyword) {
+			return nil, false
+		}
+	}
+	if k < len(tokens) && tokens[k].Kind == TokIdent {
+		ref = tokens[k : k+1]
+		alias = append(Tokenize(" AS "), ref...)
+		k = skipTrivia(tokens, k+1)
+	}
+	if k >= len(tokens) || tokens[k].Kind != TokKeyword || tokens[k].Value != "USING" {
+		return nil, false
+	}
+
+	// USING list, then WHERE condition, up to RETURNING or the statement end.
+	where, end := -1, len(tokens)
+	depth := 0
+	for i := k + 1; i < len(tokens) && end == len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "WHERE" && where < 0:
+			where = i
+		case depth == 0 && (t.Kind == TokSemicolon || (t.Kind == TokKeyword && t.Value == "RETURNING")):
+			end = skipTriviaBack(tokens, i)
+		}
+	}
+	usingEnd := end
+	if where >= 0 {
+		usingEnd = where
+	}
+	using := trimTokenWhitespace(tokens[k+1 : usingEnd])
+	if len(using) == 0 {
+		return nil, false
+	}
+
+	target := append(append([]Token(nil), name...), alias...)
+	var out []Token
+	out = append(out, tokens[:del]...)
+	out = append(out, Tokenize("DELETE FROM ")...)
+	out = append(out, target...)
+	out = append(out, Tokenize(" WHERE rowid IN (SELECT ")...)
+	out = append(out, ref...)
+	out = append(out, Tokenize(".rowid FROM ")...)
+	out = append(out, target...)
+	out = append(out, Tokenize(", ")...)
+	out = append(out, using...)
+	if where >= 0 {
+		out = append(out, Tokenize(" WHERE ")...)
+		out = append(out, trimTokenWhitespace(tokens[where+1:end])...)
+	}
+	out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	out = append(out, unqualifyReturning(tokens[end:], ref[len(ref)-1])...)
+	return out, true
+}
+
+// unqualifyReturning drops the target qualifier (o.id -> id) from a RETURNING
+// clause at the top level of tokens.
+func unqualifyReturning(tokens []Token, target Token) []Token {
+	ret := -1
+	depth := 0
+	for i, t := range tokens {
+		if t.Kind == TokParen {
+			if t.Value == "(" {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 && t.Kind == TokKeyword && t.Value == "RETURNING" {
+			ret = i
+			break
+		}
+	}
+	if ret < 0 {
+		return tokens
+	}
+	out := append([]Token(nil), tokens[:ret]...)
+	for i := ret; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind == target.Kind && strings.EqualFold(t.Value, target.Value) && i+1 < len(tokens) && tokens[i+1].Kind == TokDot &&
+			(i == 0 || tokens[i-1].Kind != TokDot) {
+			i++
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// translateUpdateAlias spells out the target alias of an UPDATE, which SQLite
+// only accepts after AS, and drops ONLY:
+//
+//	UPDATE [ONLY] orders o SET total = o.total - c.discount FROM customers c WHERE ... RETURNING o.id
+//	  -> UPDATE orders AS o SET total = o.total - c.discount FROM customers c WHERE ... RETURNING id
+//
+// As for DELETE ... USING, RETURNING loses the alias qualifier.
+func translateUpdateAlias(tokens []Token) []Token {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "UPDATE" && isStatementVerb(tokens, i):
+			return rewriteUpdateAlias(tokens, i)
+		}
+	}
+	return tokens
+}
+
+func rewriteUpdateAlias(tokens []Token, upd int) []Token {
+	j := skipTrivia(tokens, upd+1)
+	only := -1
+	if k, ok := peekKeyword(tokens, upd+1, "ONLY"); ok {
+		only, j = k, skipTrivia(tokens, k+1)
+	}
+	nameEnd := j
+	for nameEnd < len(tokens) && (tokens[nameEnd].Kind == TokIdent || tokens[nameEnd].Kind == TokDot ||
+		(tokens[nameEnd].Kind == TokKeyword && tokens[nameEnd].Value != "SET")) {
+		nameEnd++
+	}
+	if nameEnd == j {
+		return tokens
+	}
+	alias := skipTrivia(tokens, nameEnd)
+	hasAlias := alias < len(tokens) && tokens[alias].Kind == TokIdent
+	if only < 0 && !hasAlias {
+		return tokens
+	}
+
+	var out []Token
+	out = append(out, tokens[:upd+1]...)
+	out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+	out = append(out, tokens[j:nameEnd]...)
+	if !hasAlias {
+		return append(out, tokens[nameEnd:]...)
+	}
+	out = append(out, Tokenize(" AS")...)
+	out = append(out, unqualifyReturning(tokens[nameEnd:], tokens[alias])...)
+	return out
+}
+
+// translateMultiColumnSet expands multi-column SET assignments into individual ones:
+//
+//	SET (a, b) = (1, 2)                      -> SET a = 1, b = 2
+//	SET (a, b) = ROW(1, 2)                   -> SET a = 1, b = 2
+//	SET (a, b) = (SELECT x, y FROM s WHERE c) -> SET a = (SELECT x FROM s WHERE c), b = (SELECT y FROM s WHERE c)
+//
+// Assignments that don't match these shapes are left untouched.
+func translateMultiColumnSet(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "SET" {
+			out = append(out, tokens[i])
+			continue
+		}
+		out = append(out, tokens[i])
+
+		// Find the end of the assignment list: WHERE/FROM/RETURNING at the same
+		// depth, or a closing paren that ends an enclosing group.
+		end := i + 1
+		depth := 0
+	scan:
+		for ; end < len(tokens); end++ {
+			t := tokens[end]
+			switch {
+			case t.Kind == TokParen && t.Value == "(":
+				depth++
+			case t.Kind == TokParen && t.Value == ")":
+				if depth == 0 {
+					break scan
+				}
+				depth--
+			case depth == 0 && t.Kind == TokSemicolon:
+				break scan
+			case depth == 0 && t.Kind == TokKeyword && (t.Value == "WHERE" || t.Value == "FROM" || t.Value == "RETURNING"):
+				break scan
+			}
+		}
+
+		for ai, piece := range splitTopLevel(tokens[i+1 : end]) {
+			if ai > 0 {
+				out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+			}
+			if expanded, ok := expandRowAssignment(piece); ok {
+				out = append(out, expanded...)
+			} else {
+				out = append(out, piece...)
+			}
+		}
+		i = end - 1
+	}
+	return out
+}
+
+// expandRowAssignment rewrites a single "(cols) = (vals)" assignment, keeping
+// the piece's surrounding whitespace. Returns false if the piece isn't a row assignment.
+func expandRowAssignment(piece []Token) ([]Token, bool) {
+	start := 0
+	for start < len(piece) && piece[start].Kind == TokWhitespace {
+		start++
+	}
+	stop := len(piece)
+	for stop > start && piece[stop-1].Kind == TokWhitespace {
+		stop--
+	}
+	body := piece[start:stop]
+	if len(body) == 0 || body[0].Kind != TokParen || body[0].Value != "(" {
+		return nil, false
+	}
+
+	cols, closeIdx := parseFuncArgs(body, 0)
+	if len(cols) == 0 {
+		return nil, false
+	}
+	for _, c := range cols {
+		if len(c) != 1 || (c[0].Kind != TokIdent && c[0].Kind != TokKeyword) {
+			return nil, false
+		}
+	}
+
+	j := closeIdx + 1
+	for j < len(body) && body[j].Kind == TokWhitespace {
+		j++
+	}
+	if j >= len(body) || body[j].Kind != TokOperator || body[j].Value != "=" {
+		return nil, false
+	}
+	j++
+	for j < len(body) && body[j].Kind == TokWhitespace {
+		j++
+	}
+	// Optional ROW keyword before the value list.
+	if j < len(body) && body[j].Kind == TokKeyword && body[j].Value == "ROW" {
+		j++
+		for j < len(body) && body[j].Kind == TokWhitespace {
+			j++
+		}
+	}
+	if j >= len(body) || body[j].Kind != TokParen || body[j].Value != "(" {
+		return nil, false
+	}
+	vals, endIdx := parseFuncArgs(body, j)
+	if endIdx != len(body)-1 {
+		return nil, false
+	}
+
+	var rhs [][]Token
+	if inner := trimTokenWhitespace(body[j+1 : endIdx]); len(inner) > 0 && inner[0].Kind == TokKeyword && inner[0].Value == "SELECT" {
+		if !splittableSubquery(inner) {
+			// SQLite assigns a row-valued subquery itself; only splitting
+			// would change which row each column comes from.
+			return nil, false
+		}
+		rhs = splitSubqueryColumns(inner)
+	} else {
+		rhs = vals
+	}
+	if len(rhs) != len(cols) {
+		return nil, false
+	}
+
+	var result []Token
+	result = append(result, piece[:start]...)
+	for ci, col := range cols {
+		if ci > 0 {
+			result = append(result,
+				Token{Kind: TokComma, Value: ",", Raw: ","},
+				Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+			)
+		}
+		result = append(result, col[0],
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+			Token{Kind: TokOperator, Value: "=", Raw: "="},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		result = append(result, rhs[ci]...)
+	}
+	result = append(result, piece[stop:]...)
+	return result, true
+}
+
+// splitSubqueryColumns turns "SELECT x, y FROM rest" into one scalar subquery per
+// select-list item: "(SELECT x FROM rest)", "(SELECT y FROM rest)".
+func splitSubqueryColumns(sub []Token) [][]Token {
+	fromIdx := len(sub)
+	depth := 0
+	for k := 1; k < len(sub); k++ {
+		t := sub[k]
+		if t.Kind == TokParen && t.Value == "(" {
+			depth++
+		} else if t.Kind == TokParen && t.Value == ")" {
+			depth--
+		} else if depth == 0 && t.Kind == TokKeyword && t.Value == "FROM" {
+			fromIdx = k
+			break
+		}
+	}
+
+	items := splitTopLevel(sub[1:fromIdx])
+	rest := sub[fromIdx:]
+	result := make([][]Token, 0, len(items))
+	for _, item := range items {
+		item = trimTokenWhitespace(item)
+		var q []Token
+		q = append(q,
+			Token{Kind: TokParen, Value: "(", Raw: "("},
+			Token{Kind: TokKeyword, Value: "SELECT", Raw: "SELECT"},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		q = append(q, item...)
+		if len(rest) > 0 {
+			q = append(q, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+			q = append(q, rest...)
+		}
+		q = append(q, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		result = append(result, q)
+	}
+	return result
+}
+
+// volatileFuncs are the functions whose result may differ between calls in
+// one statement.
+var volatileFuncs = map[string]bool{
+	"random": true, "gen_random_uuid": true, "uuid_generate_v4": true,
+	"nextval": true, "setval": true, "clock_timestamp": true, "timeofday": true,
+}
+
+// splittableSubquery reports whether splitSubqueryColumns may run sub once per
+// column without changing the result: a top-level DISTINCT, LIMIT or OFFSET,
+// or a volatile function call anywhere, could pick a different row each time.
+func splittableSubquery(sub []Token) bool {
+	depth := 0
+	for _, t := range sub {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case t.Kind == TokKeyword && depth == 0 && (t.Value == "DISTINCT" || t.Value == "LIMIT" || t.Value == "OFFSET"):
+			return false
+		}
+	}
+	return !hasVolatileCall(sub)
+}
+
+// hasVolatileCall reports whether tokens call one of volatileFuncs.
+func hasVolatileCall(tokens []Token) bool {
+	for k, t := range tokens {
+		if t.Kind == TokIdent && volatileFuncs[strings.ToLower(t.Value)] {
+			if n := skipTrivia(tokens, k+1); n < len(tokens) && tokens[n].Kind == TokParen && tokens[n].Value == "(" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitTopLevel splits tokens on commas that are not nested inside parentheses.
+// Whitespace around each piece is preserved.
+func splitTopLevel(tokens []Token) [][]Token {
+	var pieces [][]Token
+	var current []Token
+	depth := 0
+	for _, t := range tokens {
+		if t.Kind == TokParen && t.Value == "(" {
+			depth++
+		} else if t.Kind == TokParen && t.Value == ")" {
+			depth--
+		} else if t.Kind == TokComma && depth == 0 {
+			pieces = append(pieces, current)
+			current = nil
+			continue
+		}
+		current = append(current, t)
+	}
+	return append(pieces, current)
+}
+
+// translateReturningNames aliases unaliased RETURNING expressions with the
+// output column names PostgreSQL gives them, since SQLite names such columns
+// after their (translated) text:
+//
+//	RETURNING id, id + 1, upper(name), name::text
+//	-> RETURNING id, id + 1 AS "?column?", upper(name) AS "upper", name::text AS "name"
+//
+// Plain column references, * and aliased items are left alone.
+func translateReturningNames(tokens []Token) []Token {
+	ret, depth := -1, 0
+	for i, t := range tokens {
+		if t.Kind == TokParen && t.Value == "(" {
+			depth++
+		} else if t.Kind == TokParen && t.Value == ")" {
+			depth--
+		} else if depth == 0 && t.Kind == TokKeyword && t.Value == "RETURNING" {
+			ret = i
+			break
+		}
+	}
+	if ret < 0 {
+		return tokens
+	}
+	end := ret + 1
+	for end < len(tokens) && tokens[end].Kind != TokSemicolon {
+		end++
+	}
+
+	out := append([]Token(nil), tokens[:ret+1]...)
+	for n, item := range splitTopLevel(tokens[ret+1 : end]) {
+		if n > 0 {
+			out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+		}
+		name, ok := returningName(item)
+		if !ok {
+			out = append(out, item...)
+			continue
+		}
+		last := skipTriviaBack(item, len(item))
+		alias := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		out = append(out, item[:last]...)
+		out = append(out,
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+			Token{Kind: TokKeyword, Value: "AS", Raw: "AS"},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+			Token{Kind: TokIdent, Value: alias, Raw: alias},
+		)
+		out = append(out, item[last:]...)
+	}
+	return append(out, tokens[end:]...)
+}
+
+// returningName returns the name PostgreSQL gives the unaliased output
+// expression item, and false when the item needs no alias.
+func returningName(item []Token) (string, bool) {
+	var sig []Token
+	for _, t := range item {
+		if t.Kind != TokWhitespace && t.Kind != TokComment {
+			sig = append(sig, t)
+		}
+	}
+	if len(sig) == 0 || isColumnRef(sig) || sig[len(sig)-1].Value == "*" || hasOutputAlias(sig) {
+		return "", false
+	}
+	return pgOutputName(sig), true
+}
+
+// isColumnRef reports whether sig is a plain, optionally qualified, column name.
+func isColumnRef(sig []Token) bool {
+	for i, t := range sig {
+		if i%2 == 1 && t.Kind != TokDot || i%2 == 0 && t.Kind != TokIdent && t.Kind != TokKeyword {
+			return false
+		}
+	}
+	return len(sig)%2 == 1
+}
+
+// hasOutputAlias reports whether the expression sig ends in an alias, with or
+// without AS.
+func hasOutputAlias(sig []Token) bool {
+	n := len(sig)
+	if n < 2 {
+		return false
+	}
+	prev := sig[n-2]
+	if prev.Kind == TokKeyword && prev.Value == "AS" {
+		return true // the alias may be a keyword: AS next
+	}
+	if sig[n-1].Kind != TokIdent {
+		return false
+	}
+	switch prev.Kind {
+	case TokIdent, TokNumber, TokString, TokParam:
+		return true
+	case TokParen:
+		return prev.Value == ")"
+	}
+	return false
+}
+
+// pgOutputName derives PostgreSQL's name for the output expression sig:
+// the column for a column reference, the function for a call, the operand (or
+// else the type) for a cast, "case" for CASE, and "?column?" otherwise.
+func pgOutputName(sig []Token) string {
+	n := len(sig)
+	if isColumnRef(sig) {
+		return identName(sig[n-1])
+	}
+	// Cast: name the operand, falling back to the type.
+	depth := 0
+	for i := n - 1; i > 0; i-- {
+		t := sig[i]
+		if t.Kind == TokParen && t.Value == ")" {
+			depth++
+		} else if t.Kind == TokParen && t.Value == "(" {
+			depth--
+		} else if depth == 0 && t.Kind == TokOperator && t.Value == "::" {
+			if name := pgOutputName(sig[:i]); name != "?column?" {
+				return name
+			}
+			return pgTypeOutputName(sig[i+1:])
+		}
+	}
+	if sig[0].Kind == TokParen && sig[0].Value == "(" && skipParenGroup(sig, 0) == n-1 {
+		return pgOutputName(sig[1 : n-1])
+	}
+	if sig[0].Kind == TokKeyword && sig[0].Value == "CASE" && sig[n-1].Kind == TokKeyword && sig[n-1].Value == "END" {
+		return "case"
+	}
+	if n >= 3 && (sig[0].Kind == TokIdent || sig[0].Kind == TokKeyword) &&
+		sig[1].Kind == TokParen && sig[1].Value == "(" && skipParenGroup(sig, 1) == n-1 {
+		if sig[0].Kind == TokKeyword && sig[0].Value == "CAST" {
+			// CAST(x AS type) is named like x::type.
+			for i := n - 2; i > 1; i-- {
+				if sig[i].Kind == TokKeyword && sig[i].Value == "AS" {
+					if name := pgOutputName(sig[2:i]); name != "?column?" {
+						return name
+					}
+					return pgTypeOutputName(sig[i+1 : n-1])
+				}
+			}
+		}
+		return identName(sig[0])
+	}
+	return "?column?"
+}
+
+// pgTypeOutputNames maps type names to the internal names PostgreSQL uses
+// for a cast column with no better name.
+var pgTypeOutputNames = map[string]string{
+	"INTEGER": "int4", "INT": "int4", "SMALLINT": "int2", "BIGINT": "int8",
+	"BOOLEAN": "bool", "REAL": "float4", "DOUBLE": "float8", "DECIMAL": "numeric",
+	"VARCHAR": "varchar", "CHARACTER": "bpchar", "CHAR": "bpchar",
+}
+
+// pgTypeOutputName returns PostgreSQL's output name for a cast to the type
+// whose tokens begin typ.
+func pgTypeOutputName(typ []Token) string {
+	if len(typ) == 0 {
+		return "?column?"
+	}
+	if name, ok := pgTypeOutputNames[strings.ToUpper(typ[0].Value)]; ok {
+		return name
+	}
+	return identName(typ[0])
+}
+
+// identName returns the name an identifier token denotes: quoted names
+// verbatim, unquoted ones folded to lower case.
+func identName(t Token) string {
+	if strings.HasPrefix(t.Value, `"`) {
+		return strings.ReplaceAll(strings.Trim(t.Value, `"`), `""`, `"`)
+	}
+	return strings.ToLower(t.Value)
+}
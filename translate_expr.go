@@ -3,18 +3,404 @@ package pglike
 import "strings"
 
 // translateExpressions handles expression-level translations:
-// ::cast, ILIKE, TRUE/FALSE literals, E'strings', IS TRUE/FALSE.
-func translateExpressions(tokens []Token) []Token {
+// ROW(...), IS DISTINCT FROM, row comparisons, ::cast, ^, #, ILIKE, TRUE/FALSE literals, E'strings', IS TRUE/FALSE.
+func translateExpressions(tokens []Token, sc *scope) []Token {
+	tokens = translateArraySubscript(tokens, sc)
+	tokens = translateToJSONBooleans(tokens, sc)
+	tokens = translateRowConstructors(tokens)
+	tokens = translateIsDistinctFrom(tokens)
+	tokens = translateRowComparison(tokens)
+	tokens = translateLikeOps(tokens)
+	// :: binds tighter than the operators rewritten to calls below, so casts
+	// go first and an operand such as body::tsvector stays whole.
+	tokens = translateCast(tokens, sc)
+	tokens = translateTsMatch(tokens)
 	tokens = translateRegexOps(tokens)
 	tokens = translateSimilarTo(tokens)
-	tokens = translateCast(tokens)
+	tokens = translatePower(tokens)
+	tokens = translateDivMod(tokens)
+	tokens = translateBitXor(tokens)
 	tokens = translateILIKE(tokens)
+	tokens = translateLikeEscape(tokens)
 	tokens = translateEscapeStrings(tokens)
 	tokens = translateIsTrueFalse(tokens)
 	tokens = translateBooleans(tokens)
 	return tokens
 }
 
+// translateArraySubscript rewrites subscripts and slices of array columns to
+// pg_array_get and pg_array_slice, which take PG's 1-based indexes and accept
+// the array as JSON or as a PG literal such as '{a,b}':
+//
+//	tags[1]       -> pg_array_get(tags, 1)
+//	t.grid[1][2]  -> pg_array_get(t.grid, 1, 2)
+//	tags[2:3]     -> pg_array_slice(tags, 2, 3)
+//	grid[1:2][2]  -> pg_array_slice(grid, 1, 2, 1, 2)
+//
+// As in PG, once any subscript is a slice every one is: a plain n stands for
+// 1:n, and an omitted bound for the array's end. Only columns declared with an
+// array type in a CREATE TABLE run through the driver are rewritten.
+func translateArraySubscript(tokens []Token, sc *scope) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind != TokIdent || !isSubscriptOpen(tokens, i+1) {
+			out = append(out, t)
+			continue
+		}
+		table, start := "", len(out)
+		if n := len(out); n >= 2 && out[n-1].Kind == TokDot && out[n-2].Kind == TokIdent {
+			table, start = identName(out[n-2]), n-2
+		}
+		if typ, ok := sc.columnType(table, identName(t)); !ok || !strings.HasSuffix(typ, "[]") {
+			out = append(out, t)
+			continue
+		}
+
+		// Each subscript as its lower and upper bound; a plain index has no
+		// lower bound.
+		var lower, upper []string
+		slice := false
+		end := i
+		for isSubscriptOpen(tokens, end+1) {
+			close, colon := subscriptClose(tokens, end+1)
+			if close < 0 {
+				break
+			}
+			lo, hi := "", reassembleInline(trimTokenWhitespace(tokens[end+2:close]))
+			if colon >= 0 {
+				slice = true
+				lo = reassembleInline(trimTokenWhitespace(tokens[end+2 : colon]))
+				hi = reassembleInline(trimTokenWhitespace(tokens[colon+1 : close]))
+				if lo == "" {
+					lo = "1"
+				}
+				if hi == "" {
+					hi = "9223372036854775807"
+				}
+			}
+			lower, upper = append(lower, lo), append(upper, hi)
+			end = close
+		}
+		if end == i {
+			out = append(out, t)
+			continue
+		}
+		call := "pg_array_get(" + Reassemble(out[start:]) + t.Raw
+		if slice {
+			call = "pg_array_slice(" + Reassemble(out[start:]) + t.Raw
+		}
+		for k := range upper {
+			if slice && lower[k] == "" {
+				lower[k] = "1"
+			}
+			if slice {
+				call += ", " + lower[k]
+			}
+			call += ", " + upper[k]
+		}
+		out = append(out[:start], Tokenize(call+")")...)
+		i = end
+	}
+	return out
+}
+
+// isSubscriptOpen reports whether tokens[i] is a [ opening a subscript.
+func isSubscriptOpen(tokens []Token, i int) bool {
+	return i < len(tokens) && tokens[i].Kind == TokBracket && tokens[i].Value == "["
+}
+
+// subscriptClose returns the index of the ] closing the subscript opened at
+// tokens[open], or -1 if it is unclosed, and the index of the : of a slice, or
+// -1 for a plain subscript.
+func subscriptClose(tokens []Token, open int) (int, int) {
+	depth, colon := 0, -1
+	for j := open; j < len(tokens); j++ {
+		switch t := tokens[j]; {
+		case t.Kind == TokBracket && t.Value == "[":
+			depth++
+		case t.Kind == TokBracket:
+			if depth--; depth == 0 {
+				return j, colon
+			}
+		case t.Kind == TokOperator && t.Value == ":":
+			if depth == 1 && colon < 0 {
+				colon = j
+			}
+		}
+	}
+	return -1, -1
+}
+
+// translateRowConstructors drops the ROW keyword of a row constructor:
+// ROW(a, b) -> (a, b). FOR EACH ROW is not followed by a paren and is left alone.
+func translateRowConstructors(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == TokKeyword && tokens[i].Value == "ROW" && isFuncCall(tokens, i) {
+			for i+1 < len(tokens) && tokens[i+1].Kind == TokWhitespace {
+				i++
+			}
+			continue
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// translateIsDistinctFrom converts PG's null-safe comparisons to SQLite's IS:
+//
+//	a IS DISTINCT FROM b          -> a IS NOT b
+//	a IS NOT DISTINCT FROM b      -> a IS b
+//	(a, b) IS DISTINCT FROM (c, d) -> (a IS NOT c OR b IS NOT d)
+//	(a, b) IS NOT DISTINCT FROM (c, d) -> (a IS c AND b IS d)
+func translateIsDistinctFrom(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "IS" {
+			out = append(out, tokens[i])
+			continue
+		}
+		j := i + 1
+		negate := false
+		if k, ok := peekKeyword(tokens, j, "NOT"); ok {
+			negate = true
+			j = k + 1
+		}
+		k, ok := peekKeyword(tokens, j, "DISTINCT")
+		if !ok {
+			out = append(out, tokens[i])
+			continue
+		}
+		from, ok := peekKeyword(tokens, k+1, "FROM")
+		if !ok {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		// Row-valued operands expand column by column.
+		end := len(out)
+		for end > 0 && out[end-1].Kind == TokWhitespace {
+			end--
+		}
+		if end > 0 && out[end-1].Kind == TokParen && out[end-1].Value == ")" {
+			left := extractLeftExpr(out[:end])
+			right, rend := extractRightOperand(tokens, from+1)
+			if expanded, ok := expandRowDistinct(left, right, negate); ok {
+				out = append(out[:end-len(left)], expanded...)
+				i = rend
+				continue
+			}
+		}
+
+		// Scalar: IS NOT / IS.
+		out = append(out, Token{Kind: TokKeyword, Value: "IS", Raw: "IS"})
+		if !negate {
+			out = append(out,
+				Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+				Token{Kind: TokKeyword, Value: "NOT", Raw: "NOT"},
+			)
+		}
+		i = from
+	}
+	return out
+}
+
+// expandRowDistinct expands a row-valued IS [NOT] DISTINCT FROM into per-column
+// IS NOT comparisons joined by OR (or IS comparisons joined by AND when negated).
+func expandRowDistinct(left, right []Token, negate bool) ([]Token, bool) {
+	if len(left) == 0 || len(right) == 0 || left[0].Kind != TokParen || right[0].Kind != TokParen {
+		return nil, false
+	}
+	l, _ := parseFuncArgs(left, 0)
+	r, _ := parseFuncArgs(right, 0)
+	if !isRowValue(l) || len(l) != len(r) {
+		return nil, false
+	}
+
+	op, join := "IS NOT", "OR"
+	if negate {
+		op, join = "IS", "AND"
+	}
+	out := []Token{{Kind: TokParen, Value: "(", Raw: "("}}
+	for k := range l {
+		if k > 0 {
+			out = append(out, Tokenize(" "+join+" ")...)
+		}
+		out = append(out, l[k]...)
+		out = append(out, Tokenize(" "+op+" ")...)
+		out = append(out, r[k]...)
+	}
+	out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	return out, true
+}
+
+// rowCompareOps maps ordered comparison operators to their strict form.
+var rowCompareOps = map[string]string{"<": "<", "<=": "<", ">": ">", ">=": ">"}
+
+// translateRowComparison expands ordered comparisons between row values into
+// their lexicographic boolean form:
+//
+//	(a, b) < (1, 2) -> (a < 1 OR (a = 1 AND b < 2))
+//
+// Row equality and inequality pass through, since SQLite supports them directly.
+func translateRowComparison(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == TokParen && tokens[i].Value == "(" && !followsFuncName(out) {
+			if expanded, end, ok := expandRowComparison(tokens, i); ok {
+				out = append(out, expanded...)
+				i = end
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// followsFuncName reports whether a paren appended next to out would open the
+// argument list of a function call or an IN/VALUES list rather than a row value.
+func followsFuncName(out []Token) bool {
+	j := len(out) - 1
+	for j >= 0 && out[j].Kind == TokWhitespace {
+		j--
+	}
+	if j < 0 {
+		return false
+	}
+	switch out[j].Kind {
+	case TokIdent:
+		return true
+	case TokKeyword:
+		switch out[j].Value {
+		case "IN", "VALUES", "COALESCE", "NULLIF", "EXISTS":
+			return true
+		}
+	}
+	return false
+}
+
+// expandRowComparison tries to match "(l1, ..., ln) op (r1, ..., rn)" starting at
+// the open paren at index open. Returns the expansion and the index of the last
+// consumed token.
+func expandRowComparison(tokens []Token, open int) ([]Token, int, bool) {
+	left, closeL := parseFuncArgs(tokens, open)
+	if !isRowValue(left) {
+		return nil, 0, false
+	}
+	j := closeL + 1
+	for j < len(tokens) && tokens[j].Kind == TokWhitespace {
+		j++
+	}
+	if j >= len(tokens) || tokens[j].Kind != TokOperator {
+		return nil, 0, false
+	}
+	op := tokens[j].Value
+	strict, ok := rowCompareOps[op]
+	if !ok {
+		return nil, 0, false
+	}
+	j++
+	for j < len(tokens) && tokens[j].Kind == TokWhitespace {
+		j++
+	}
+	if j >= len(tokens) || tokens[j].Kind != TokParen || tokens[j].Value != "(" {
+		return nil, 0, false
+	}
+	right, closeR := parseFuncArgs(tokens, j)
+	if !isRowValue(right) || len(right) != len(left) {
+		return nil, 0, false
+	}
+	// The expansion repeats each element, which would shift positional ?
+	// parameters. Leave those to SQLite's native row-value comparison.
+	if countTokenParams(tokens[open:closeR+1]) > 0 {
+		return nil, 0, false
+	}
+	for k := range left {
+		left[k] = translateRowComparison(left[k])
+		right[k] = translateRowComparison(right[k])
+	}
+
+	ws := Token{Kind: TokWhitespace, Value: " ", Raw: " "}
+	opTok := func(v string) Token { return Token{Kind: TokOperator, Value: v, Raw: v} }
+	kw := func(v string) Token { return Token{Kind: TokKeyword, Value: v, Raw: v} }
+	lp := Token{Kind: TokParen, Value: "(", Raw: "("}
+	rp := Token{Kind: TokParen, Value: ")", Raw: ")"}
+
+	// Built from the last column outwards:
+	// (l_k STRICT r_k OR (l_k = r_k AND <rest>))
+	n := len(left)
+	var expr []Token
+	expr = append(expr, left[n-1]...)
+	expr = append(expr, ws, opTok(op), ws)
+	expr = append(expr, right[n-1]...)
+	for k := n - 2; k >= 0; k-- {
+		var e []Token
+		e = append(e, lp)
+		e = append(e, left[k]...)
+		e = append(e, ws, opTok(strict), ws)
+		e = append(e, right[k]...)
+		e = append(e, ws, kw("OR"), ws, lp)
+		e = append(e, left[k]...)
+		e = append(e, ws, opTok("="), ws)
+		e = append(e, right[k]...)
+		e = append(e, ws, kw("AND"), ws)
+		e = append(e, expr...)
+		e = append(e, rp, rp)
+		expr = e
+	}
+	return expr, closeR, true
+}
+
+// isRowValue reports whether parsed paren contents form a row value: at least
+// two elements, and not a subquery.
+func isRowValue(elems [][]Token) bool {
+	if len(elems) < 2 {
+		return false
+	}
+	for _, e := range elems {
+		if len(e) == 0 {
+			return false
+		}
+	}
+	first := elems[0][0]
+	return first.Kind != TokKeyword || first.Value != "SELECT"
+}
+
+// likeOps maps PG's operator forms of LIKE and ILIKE to the keywords.
+var likeOps = map[string]string{
+	"~~":   "LIKE",
+	"!~~":  "NOT LIKE",
+	"~~*":  "ILIKE",
+	"!~~*": "NOT ILIKE",
+}
+
+// translateLikeOps converts the operator forms of LIKE and ILIKE to the
+// keywords, which translateILIKE and translateLikeEscape then handle:
+//
+//	name ~~ 'a%'    -> name LIKE 'a%'
+//	name !~~* 'a%'  -> name NOT ILIKE 'a%'
+func translateLikeOps(tokens []Token) []Token {
+	var out []Token
+	for i, t := range tokens {
+		kw, ok := likeOps[t.Value]
+		if !ok || t.Kind != TokOperator {
+			out = append(out, t)
+			continue
+		}
+		// Keep the keywords apart from the operands: a~~'x' -> a LIKE 'x'.
+		if len(out) > 0 && out[len(out)-1].Kind != TokWhitespace {
+			kw = " " + kw
+		}
+		if i+1 < len(tokens) && tokens[i+1].Kind != TokWhitespace {
+			kw += " "
+		}
+		out = append(out, Tokenize(kw)...)
+	}
+	return out
+}
+
 // translateRegexOps converts PG regex operators to pg_regex_match() calls.
 // expr ~ pattern   -> pg_regex_match(expr, pattern, 0)
 // expr ~* pattern  -> pg_regex_match(expr, pattern, 1)
@@ -47,34 +433,18 @@ func translateRegexOps(tokens []Token) []Token {
 			continue
 		}
 
-		// Extract the left-hand expression from out (skip trailing whitespace)
-		lhsEnd := len(out)
-		for lhsEnd > 0 && out[lhsEnd-1].Kind == TokWhitespace {
-			lhsEnd--
-		}
-		if lhsEnd == 0 {
-			out = append(out, tokens[i])
-			continue
-		}
-		lhsToken := out[lhsEnd-1]
-		out = out[:lhsEnd-1] // remove LHS and any whitespace after it
-		// Also remove whitespace before LHS position (between previous token and LHS)
-		// Keep the whitespace that was before the LHS
-		// Actually we removed trailing ws already, just remove the single LHS token
-
-		// Read the right-hand expression (skip whitespace, take next non-ws token)
-		j := i + 1
-		for j < len(tokens) && tokens[j].Kind == TokWhitespace {
-			j++
-		}
-		if j >= len(tokens) {
-			// Malformed, put everything back
-			out = append(out, lhsToken)
+		// The operands: a term on each side, such as a name, a call, a
+		// parenthesized group or CASE ... END.
+		lhsEnd := skipTriviaBack(out, len(out))
+		lhs := extractLeftExpr(out[:lhsEnd])
+		rhs, rend := extractRightOperand(tokens, i+1)
+		if len(lhs) == 0 || len(rhs) == 0 {
 			out = append(out, tokens[i])
 			continue
 		}
-		rhsToken := tokens[j]
-		i = j
+		lhs = append([]Token(nil), lhs...)
+		out = out[:lhsEnd-len(lhs)]
+		i = rend
 
 		// Emit: [NOT ]pg_regex_match(lhs, rhs, flag)
 		if negated {
@@ -88,10 +458,14 @@ func translateRegexOps(tokens []Token) []Token {
 		out = append(out,
 			Token{Kind: TokIdent, Value: "pg_regex_match", Raw: "pg_regex_match"},
 			Token{Kind: TokParen, Value: "(", Raw: "("},
-			lhsToken,
+		)
+		out = append(out, lhs...)
+		out = append(out,
 			Token{Kind: TokComma, Value: ",", Raw: ","},
 			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
-			rhsToken,
+		)
+		out = append(out, rhs...)
+		out = append(out,
 			Token{Kind: TokComma, Value: ",", Raw: ","},
 			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
 			Token{Kind: TokNumber, Value: flag, Raw: flag},
@@ -101,7 +475,8 @@ func translateRegexOps(tokens []Token) []Token {
 	return out
 }
 
-// translateSimilarTo converts [NOT] SIMILAR TO pattern -> [NOT ]pg_similar_match(expr, pattern).
+// translateSimilarTo converts [NOT] SIMILAR TO pattern [ESCAPE 'c'] ->
+// [NOT ]pg_similar_match(expr, pattern[, 'c']).
 func translateSimilarTo(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
@@ -136,25 +511,25 @@ func translateSimilarTo(tokens []Token) []Token {
 			}
 		}
 
-		// Get the expression before [NOT] SIMILAR (should be an ident/value)
-		if lhsEnd == 0 {
+		// The expression before [NOT] SIMILAR and the pattern after TO.
+		lhs := extractLeftExpr(out[:lhsEnd])
+		pattern, k := extractRightOperand(tokens, j+1)
+		if len(lhs) == 0 || len(pattern) == 0 {
 			out = append(out, tokens[i])
 			continue
 		}
-		lhsToken := out[lhsEnd-1]
-		out = out[:lhsEnd-1]
+		lhs = append([]Token(nil), lhs...)
+		out = out[:lhsEnd-len(lhs)]
+		i = k
 
-		// Read pattern after TO
-		k := j + 1
-		for k < len(tokens) && tokens[k].Kind == TokWhitespace {
-			k++
-		}
-		if k >= len(tokens) {
-			out = append(out, lhsToken, tokens[i])
-			continue
+		// Optional ESCAPE 'c', passed on as the third argument.
+		var escapeToken *Token
+		if e := skipTrivia(tokens, k+1); e < len(tokens) && tokens[e].Kind == TokIdent && strings.EqualFold(tokens[e].Value, "ESCAPE") {
+			if c := skipTrivia(tokens, e+1); c < len(tokens) && tokens[c].Kind == TokString {
+				escapeToken = &tokens[c]
+				i = c
+			}
 		}
-		patternToken := tokens[k]
-		i = k
 
 		// Emit: [NOT ]pg_similar_match(expr, pattern)
 		if negated {
@@ -164,18 +539,121 @@ func translateSimilarTo(tokens []Token) []Token {
 		out = append(out,
 			Token{Kind: TokIdent, Value: "pg_similar_match", Raw: "pg_similar_match"},
 			Token{Kind: TokParen, Value: "(", Raw: "("},
-			lhsToken,
+		)
+		out = append(out, lhs...)
+		out = append(out,
 			Token{Kind: TokComma, Value: ",", Raw: ","},
 			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
-			patternToken,
-			Token{Kind: TokParen, Value: ")", Raw: ")"},
 		)
+		out = append(out, pattern...)
+		if escapeToken != nil {
+			out = append(out,
+				Token{Kind: TokComma, Value: ",", Raw: ","},
+				Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+				*escapeToken,
+			)
+		}
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	}
+	return out
+}
+
+// castFuncs maps PG types whose casts must validate or normalize their input
+// to the pg_* function (see pgfuncs.go) that performs the cast.
+var castFuncs = map[string]string{
+	"UUID":     "pg_uuid",
+	"INTERVAL": "pg_interval",
+
+	// Float casts accept 'NaN', 'Infinity' and '-Infinity'. DOUBLE PRECISION
+	// has already been rewritten to REAL by translateTypes.
+	"REAL":   "pg_float8",
+	"FLOAT":  "pg_float8",
+	"FLOAT4": "pg_float8",
+	"FLOAT8": "pg_float8",
+}
+
+// noopCastTypes are PG system types that name catalog objects. SQLite has no
+// catalog OIDs, so casts to them are dropped and the value is used as is:
+// nextval('seq'::regclass) -> nextval('seq').
+var noopCastTypes = map[string]bool{
+	"REGCLASS":     true,
+	"REGTYPE":      true,
+	"REGPROC":      true,
+	"REGPROCEDURE": true,
+	"REGNAMESPACE": true,
+	"REGROLE":      true,
+	"OID":          true,
+}
+
+// translateCastNumeric routes CAST(expr AS numeric(p,s)) through pg_numeric
+// and CAST(expr AS interval) through pg_interval, as translateCast does for
+// the :: forms. It runs before translateTypes, which would otherwise drop the
+// (p,s) modifier and map INTERVAL to its column type:
+//
+//	CAST(x AS numeric(10,2)) -> pg_numeric(x, 10, 2)
+//	CAST(x AS DECIMAL(5))    -> pg_numeric(x, 5, 0)
+//	CAST(x AS interval)      -> pg_interval(x)
+func translateCastNumeric(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		open := skipTrivia(tokens, i+1)
+		if t.Kind != TokKeyword || t.Value != "CAST" || open >= len(tokens) || tokens[open].Kind != TokParen || tokens[open].Value != "(" {
+			out = append(out, t)
+			continue
+		}
+		closeIdx := skipParenGroup(tokens, open)
+		as, depth := -1, 0
+		for k := open + 1; k < closeIdx; k++ {
+			switch {
+			case tokens[k].Kind == TokParen && tokens[k].Value == "(":
+				depth++
+			case tokens[k].Kind == TokParen && tokens[k].Value == ")":
+				depth--
+			case depth == 0 && tokens[k].Kind == TokKeyword && tokens[k].Value == "AS":
+				as = k
+			}
+		}
+		if as < 0 {
+			out = append(out, t)
+			continue
+		}
+		typ := skipTrivia(tokens, as+1)
+		paren := skipTrivia(tokens, typ+1)
+		if typ < closeIdx && tokens[typ].Kind == TokKeyword && tokens[typ].Value == "INTERVAL" && paren == closeIdx {
+			out = append(out, Token{Kind: TokIdent, Value: "pg_interval", Raw: "pg_interval"})
+			out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+			out = append(out, translateCastNumeric(trimTokenWhitespace(tokens[open+1:as]))...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+			i = closeIdx
+			continue
+		}
+		if typ >= closeIdx || tokens[typ].Kind != TokKeyword || (tokens[typ].Value != "NUMERIC" && tokens[typ].Value != "DECIMAL") ||
+			paren >= closeIdx || tokens[paren].Kind != TokParen || tokens[paren].Value != "(" ||
+			skipTrivia(tokens, skipParenGroup(tokens, paren)+1) != closeIdx {
+			out = append(out, t)
+			continue
+		}
+		mods, _ := parseFuncArgs(tokens, paren)
+		if len(mods) == 1 {
+			mods = append(mods, []Token{{Kind: TokNumber, Value: "0", Raw: "0"}})
+		}
+		out = append(out, Token{Kind: TokIdent, Value: "pg_numeric", Raw: "pg_numeric"})
+		out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+		out = append(out, translateCastNumeric(trimTokenWhitespace(tokens[open+1:as]))...)
+		for _, m := range mods[:2] {
+			out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+			out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+			out = append(out, m...)
+		}
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		i = closeIdx
 	}
 	return out
 }
 
 // translateCast converts expr::type to CAST(expr AS mapped_type).
-func translateCast(tokens []Token) []Token {
+func translateCast(tokens []Token, sc *scope) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
 		if tokens[i].Kind == TokOperator && tokens[i].Value == "::" {
@@ -189,10 +667,57 @@ func translateCast(tokens []Token) []Token {
 
 			// Read the type name to the right
 			typeTokens, end := extractTypeName(tokens, i+1)
+			mods := typeModifiers(tokens[i+1 : end+1])
 			i = end
 
 			// Map the type
 			typeName := assembleTypeName(typeTokens)
+
+			if noopCastTypes[strings.ToUpper(typeName)] {
+				out = append(out, exprTokens...)
+				continue
+			}
+			// Types that need validation are cast via a registered function.
+			if fn, ok := castFuncs[strings.ToUpper(typeName)]; ok {
+				out = append(out, Token{Kind: TokIdent, Value: fn, Raw: fn})
+				out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+				out = append(out, exprTokens...)
+				out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+				continue
+			}
+			// numeric(p, s) rounds to scale s and checks precision p; a bare
+			// NUMERIC is unconstrained and stays a plain CAST.
+			if upper := strings.ToUpper(typeName); (upper == "NUMERIC" || upper == "DECIMAL") && len(mods) > 0 {
+				if len(mods) == 1 {
+					mods = append(mods, []Token{{Kind: TokNumber, Value: "0", Raw: "0"}})
+				}
+				out = append(out, Token{Kind: TokIdent, Value: "pg_numeric", Raw: "pg_numeric"})
+				out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+				out = append(out, exprTokens...)
+				for _, m := range mods[:2] {
+					out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+					out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+					out = append(out, m...)
+				}
+				out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+				continue
+			}
+			// Enum types are validated at runtime and stored as TEXT.
+			if values, ok := sc.enum(typeName); ok {
+				lit := "'" + strings.ToLower(typeName) + "'"
+				labels := "'" + strings.ReplaceAll(enumJSON(values), "'", "''") + "'"
+				out = append(out, Token{Kind: TokIdent, Value: "pg_enum_cast", Raw: "pg_enum_cast"})
+				out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+				out = append(out, exprTokens...)
+				out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+				out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+				out = append(out, Token{Kind: TokString, Value: lit, Raw: lit})
+				out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
+				out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+				out = append(out, Token{Kind: TokString, Value: labels, Raw: labels})
+				out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+				continue
+			}
 			mappedType := mapCastType(typeName)
 
 			// Emit CAST(expr AS type)
@@ -211,9 +736,388 @@ func translateCast(tokens []Token) []Token {
 	return out
 }
 
+// translatePower converts the exponentiation operator: a ^ b -> power(a, b).
+// The call binds only the operands next to ^, so it keeps PG's precedence over
+// * and /, and chains left-associatively: 2 ^ 3 ^ 2 -> power(power(2, 3), 2).
+func translatePower(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokOperator || tokens[i].Value != "^" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		// Left operand, dropping whitespace between it and ^.
+		end := skipTriviaBack(out, len(out))
+		left := extractLeftExpr(out[:end])
+		start := end - len(left)
+		for start >= 2 && out[start-1].Kind == TokDot && out[start-2].Kind == TokIdent {
+			start -= 2 // qualified name: t.col
+		}
+		// A unary minus binds tighter than ^ in PG: -2 ^ 2 is 4.
+		if start < end {
+			j := skipTriviaBack(out, start)
+			if j > 0 && out[j-1].Kind == TokOperator && out[j-1].Value == "-" {
+				k := skipTriviaBack(out, j-1)
+				if k == 0 || !isOperandEnd(out[k-1]) {
+					start = j - 1
+				}
+			}
+		}
+		r := skipTrivia(tokens, i+1)
+		right, rend := extractRightOperand(tokens, r)
+		if start == end || len(right) == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		leftTokens := make([]Token, end-start)
+		copy(leftTokens, out[start:end])
+		comments := append(commentTokens(out[end:]), commentTokens(tokens[i+1:r])...)
+		out = out[:start]
+		out = append(out,
+			Token{Kind: TokIdent, Value: "power", Raw: "power"},
+			Token{Kind: TokParen, Value: "(", Raw: "("},
+		)
+		out = append(out, leftTokens...)
+		out = append(out,
+			Token{Kind: TokComma, Value: ",", Raw: ","},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		out = append(out, right...)
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		out = appendComments(out, comments)
+		i = rend
+	}
+	return out
+}
+
+// translateDivMod converts the division and modulo operators:
+//
+//	a / b -> pg_div(a, b)
+//	a % b -> pg_mod(a, b)
+//
+// SQLite yields NULL for a zero divisor, where PG raises division_by_zero, and
+// its % truncates REAL operands to integers, where PG keeps the fraction (5.5
+// % 2 is 1.5). Like PG's, the remainder takes the sign of the dividend. * has
+// the same precedence, so a chain of it to the left becomes the first
+// argument: a * b % c -> pg_mod(a * b, c). Earlier / and % in the chain have
+// already become calls by then.
+//
+// Expressions SQLite keeps in the schema, such as CHECK constraints, generated
+// columns and index expressions, are left to SQLite's operators (see
+// isSchemaDDL), so that the schema doesn't need pglike's functions.
+func translateDivMod(tokens []Token) []Token {
+	if isSchemaDDL(tokens) {
+		return tokens
+	}
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokOperator || (tokens[i].Value != "%" && tokens[i].Value != "/") {
+			out = append(out, tokens[i])
+			continue
+		}
+		fn := "pg_mod"
+		if tokens[i].Value == "/" {
+			fn = "pg_div"
+		}
+
+		end := skipTriviaBack(out, len(out))
+		start := end - len(extractLeftExpr(out[:end]))
+		for start < end {
+			op := skipTriviaBack(out, start) - 1
+			if op < 1 || out[op].Kind != TokOperator || out[op].Value != "*" {
+				break
+			}
+			prevEnd := skipTriviaBack(out, op)
+			prev := extractLeftExpr(out[:prevEnd])
+			if len(prev) == 0 {
+				break
+			}
+			start = prevEnd - len(prev)
+		}
+		r := skipTrivia(tokens, i+1)
+		right, rend := extractRightOperand(tokens, r)
+		if start == end || len(right) == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		leftTokens := make([]Token, end-start)
+		copy(leftTokens, out[start:end])
+		comments := append(commentTokens(out[end:]), commentTokens(tokens[i+1:r])...)
+		out = out[:start]
+		out = append(out,
+			Token{Kind: TokIdent, Value: fn, Raw: fn},
+			Token{Kind: TokParen, Value: "(", Raw: "("},
+		)
+		out = append(out, leftTokens...)
+		out = append(out,
+			Token{Kind: TokComma, Value: ",", Raw: ","},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		out = append(out, right...)
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		out = appendComments(out, comments)
+		i = rend
+	}
+	return out
+}
+
+// isSchemaDDL reports whether tokens are a statement whose expressions SQLite
+// keeps in the schema: CREATE TABLE, other than CREATE TABLE ... AS, ALTER
+// TABLE and CREATE INDEX.
+func isSchemaDDL(tokens []Token) bool {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword || (tokens[i].Value != "CREATE" && tokens[i].Value != "ALTER") {
+		return false
+	}
+	for j := i + 1; j < len(tokens); j++ {
+		switch t := tokens[j]; {
+		case t.Kind == TokKeyword && t.Value == "INDEX":
+			return true
+		case t.Kind == TokKeyword && t.Value == "TABLE":
+			if tokens[i].Value == "ALTER" {
+				return true
+			}
+			// CREATE TABLE t AS SELECT ... runs its query once.
+			for k := j + 1; k < len(tokens) && !(tokens[k].Kind == TokParen && tokens[k].Value == "("); k++ {
+				if tokens[k].Kind == TokKeyword && tokens[k].Value == "AS" {
+					return false
+				}
+			}
+			return true
+		case t.Kind != TokWhitespace && t.Kind != TokKeyword:
+			return false
+		}
+	}
+	return false
+}
+
+// arithOps are the operators that bind tighter than PG's "other" operators such as #.
+var arithOps = map[string]bool{"+": true, "-": true, "*": true, "/": true, "%": true}
+
+// translateBitXor converts PG's bitwise XOR operator: a # b -> pg_bitxor(a, b).
+// Like PostgreSQL, arithmetic binds tighter than #, so 1 + 2 # 3 is
+// pg_bitxor(1 + 2, 3), and chains are left-associative.
+func translateBitXor(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokOperator || tokens[i].Value != "#" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		end := skipTriviaBack(out, len(out))
+		start := arithLeftStart(out, end)
+		r := skipTrivia(tokens, i+1)
+		right, rend := extractRightOperand(tokens, r)
+		if start == end || len(right) == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+		comments := append(commentTokens(out[end:]), commentTokens(tokens[i+1:r])...)
+		// Extend the right operand across arithmetic: a # b + 1 -> pg_bitxor(a, b + 1)
+		for {
+			j := rend + 1
+			for j < len(tokens) && tokens[j].Kind == TokWhitespace {
+				j++
+			}
+			if j >= len(tokens) || tokens[j].Kind != TokOperator || !arithOps[tokens[j].Value] {
+				break
+			}
+			next, nend := extractRightOperand(tokens, j+1)
+			if len(next) == 0 {
+				break
+			}
+			right = tokens[r : nend+1]
+			rend = nend
+		}
+		right = trimTokenWhitespace(right)
+
+		leftTokens := make([]Token, end-start)
+		copy(leftTokens, out[start:end])
+		out = out[:start]
+		out = append(out,
+			Token{Kind: TokIdent, Value: "pg_bitxor", Raw: "pg_bitxor"},
+			Token{Kind: TokParen, Value: "(", Raw: "("},
+		)
+		out = append(out, leftTokens...)
+		out = append(out,
+			Token{Kind: TokComma, Value: ",", Raw: ","},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		out = append(out, right...)
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		out = appendComments(out, comments)
+		i = rend
+	}
+	return out
+}
+
+// skipTrivia returns the index of the first token at or after i that is
+// neither whitespace nor a comment.
+func skipTrivia(tokens []Token, i int) int {
+	for i < len(tokens) && (tokens[i].Kind == TokWhitespace || tokens[i].Kind == TokComment) {
+		i++
+	}
+	return i
+}
+
+// skipTriviaBack returns end moved back past trailing whitespace and comments.
+func skipTriviaBack(tokens []Token, end int) int {
+	for end > 0 && (tokens[end-1].Kind == TokWhitespace || tokens[end-1].Kind == TokComment) {
+		end--
+	}
+	return end
+}
+
+// commentTokens returns the comments among tokens.
+func commentTokens(tokens []Token) []Token {
+	var comments []Token
+	for _, t := range tokens {
+		if t.Kind == TokComment {
+			comments = append(comments, t)
+		}
+	}
+	return comments
+}
+
+// appendComments re-emits comments displaced by a rewrite, each preceded by a space.
+func appendComments(out, comments []Token) []Token {
+	for _, c := range comments {
+		out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "}, c)
+	}
+	return out
+}
+
+// arithLeftStart returns the index in out where the arithmetic expression
+// ending at end begins: operands (names, literals, calls, groups) joined by
+// + - * / %, plus an optional leading sign.
+func arithLeftStart(out []Token, end int) int {
+	start := end
+	for {
+		if start == 0 || !isOperandEnd(out[start-1]) {
+			return start
+		}
+		operand := extractLeftExpr(out[:start])
+		s := start - len(operand)
+		for s >= 2 && out[s-1].Kind == TokDot && out[s-2].Kind == TokIdent {
+			s -= 2
+		}
+		start = s
+
+		j := start
+		for j > 0 && out[j-1].Kind == TokWhitespace {
+			j--
+		}
+		if j == 0 || out[j-1].Kind != TokOperator || !arithOps[out[j-1].Value] {
+			return start
+		}
+		op := j - 1
+		k := op
+		for k > 0 && out[k-1].Kind == TokWhitespace {
+			k--
+		}
+		if k == 0 || !isOperandEnd(out[k-1]) {
+			// Unary sign.
+			if out[op].Value == "-" || out[op].Value == "+" {
+				return op
+			}
+			return start
+		}
+		start = k
+	}
+}
+
+// isOperandEnd reports whether t can end an operand.
+func isOperandEnd(t Token) bool {
+	switch t.Kind {
+	case TokIdent, TokNumber, TokParam, TokString:
+		return true
+	case TokParen:
+		return t.Value == ")"
+	case TokKeyword:
+		return t.Value == "NULL" || t.Value == "TRUE" || t.Value == "FALSE"
+	}
+	return false
+}
+
+// extractRightOperand reads a single operand starting at start (after optional
+// whitespace): an optionally signed literal, parameter, (qualified) name,
+// function call or parenthesized group. Returns the operand tokens and the index
+// of the last one.
+func extractRightOperand(tokens []Token, start int) ([]Token, int) {
+	i := start
+	for i < len(tokens) && tokens[i].Kind == TokWhitespace {
+		i++
+	}
+	begin := i
+	if i < len(tokens) && tokens[i].Kind == TokOperator && (tokens[i].Value == "-" || tokens[i].Value == "+") {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil, start
+	}
+
+	t := tokens[i]
+	switch {
+	case t.Kind == TokParen && t.Value == "(":
+		i = skipParenGroup(tokens, i)
+	case t.Kind == TokKeyword && t.Value == "CASE":
+		if i = caseEnd(tokens, i); i < 0 {
+			return nil, start
+		}
+	case t.Kind == TokIdent || t.Kind == TokKeyword:
+		for i+2 < len(tokens) && tokens[i+1].Kind == TokDot && tokens[i+2].Kind == TokIdent {
+			i += 2
+		}
+		if isFuncCall(tokens, i) {
+			i = skipParenGroup(tokens, i+1)
+		}
+	case t.Kind == TokNumber || t.Kind == TokParam || t.Kind == TokString:
+	default:
+		return nil, start
+	}
+	return tokens[begin : i+1], i
+}
+
+// caseEnd returns the index of the END closing the CASE at tokens[start], or
+// -1 if it is unclosed.
+func caseEnd(tokens []Token, start int) int {
+	depth := 0
+	for j := start; j < len(tokens); j++ {
+		if tokens[j].Kind != TokKeyword {
+			continue
+		}
+		switch tokens[j].Value {
+		case "CASE":
+			depth++
+		case "END":
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
 // extractLeftExpr extracts the expression to the left of :: from the output tokens.
 // The expression can be: a simple value/ident, a string literal, a number, or a parenthesized group.
+// Identifiers and function names keep their qualifiers (t.col, EXCLUDED.col, schema.f(...)).
 func extractLeftExpr(out []Token) []Token {
+	expr := extractLeftTerm(out)
+	start := len(out) - len(expr)
+	if len(expr) > 0 && (expr[0].Kind == TokIdent || expr[0].Kind == TokKeyword) {
+		for start >= 2 && out[start-1].Kind == TokDot && (out[start-2].Kind == TokIdent || out[start-2].Kind == TokKeyword) {
+			start -= 2
+		}
+	}
+	return out[start:]
+}
+
+// extractLeftTerm is extractLeftExpr without the qualifiers.
+func extractLeftTerm(out []Token) []Token {
 	if len(out) == 0 {
 		return nil
 	}
@@ -221,34 +1125,83 @@ func extractLeftExpr(out []Token) []Token {
 
 	// If parenthesized group, find matching open paren
 	if last.Kind == TokParen && last.Value == ")" {
-		depth := 1
-		j := len(out) - 2
-		for j >= 0 && depth > 0 {
-			if out[j].Kind == TokParen && out[j].Value == ")" {
-				depth++
-			} else if out[j].Kind == TokParen && out[j].Value == "(" {
-				depth--
-			}
-			if depth > 0 {
-				j--
+		j := openingIndex(out, TokParen, "(", ")")
+		if j < 0 {
+			return out[len(out)-1:]
+		}
+		k := prevSignificant(out, j)
+		if k < 0 {
+			return out[j:]
+		}
+		// A window or filter clause belongs to the call before it:
+		// sum(x) OVER (...), count(*) FILTER (WHERE ...).
+		if (out[k].Kind == TokKeyword || out[k].Kind == TokIdent) && (out[k].Value == "OVER" || strings.EqualFold(out[k].Value, "FILTER")) {
+			end := skipTriviaBack(out, k)
+			if call := extractLeftExpr(out[:end]); len(call) > 0 {
+				return out[end-len(call):]
 			}
 		}
-		// Include any function name before the paren
-		if j > 0 && (out[j-1].Kind == TokIdent || out[j-1].Kind == TokKeyword) {
-			j--
+		// Include any function name before the paren: f(x), or f (x) for a
+		// name that is not a keyword.
+		if out[k].Kind == TokIdent || (out[k].Kind == TokKeyword && k == j-1) {
+			j = k
 		}
 		return out[j:]
 	}
 
-	// Simple: ident, string, number, keyword, param
-	switch last.Kind {
-	case TokIdent, TokString, TokNumber, TokKeyword, TokParam:
+	// CASE ... END is a single term.
+	if last.Kind == TokKeyword && last.Value == "END" {
+		depth := 0
+		for j := len(out) - 1; j >= 0; j-- {
+			if out[j].Kind != TokKeyword {
+				continue
+			}
+			switch out[j].Value {
+			case "END":
+				depth++
+			case "CASE":
+				if depth--; depth == 0 {
+					return out[j:]
+				}
+			}
+		}
 		return out[len(out)-1:]
 	}
 
+	// A subscript belongs to the (qualified) name or group before it: t.tags[1].
+	if last.Kind == TokBracket && last.Value == "]" {
+		j := openingIndex(out, TokBracket, "[", "]")
+		if j <= 0 {
+			return out[len(out)-1:]
+		}
+		term := extractLeftExpr(out[:j])
+		return out[j-len(term):]
+	}
+
+	// Simple: ident, string, number, keyword, param
 	return out[len(out)-1:]
 }
 
+// openingIndex returns the index of the open token matching the close token
+// that ends out, or -1 if there is none.
+func openingIndex(out []Token, kind TokenKind, open, close string) int {
+	depth := 0
+	for j := len(out) - 1; j >= 0; j-- {
+		if out[j].Kind != kind {
+			continue
+		}
+		switch out[j].Value {
+		case close:
+			depth++
+		case open:
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
 // extractTypeName reads a type name starting at position start.
 // Returns the tokens making up the type name and the last index consumed.
 func extractTypeName(tokens []Token, start int) ([]Token, int) {
@@ -308,6 +1261,18 @@ func extractTypeName(tokens []Token, start int) ([]Token, int) {
 	return result, i - 1
 }
 
+// typeModifiers returns the arguments of the (n) or (n,m) modifier in the type
+// tokens read by extractTypeName, or nil when the type has none.
+func typeModifiers(tokens []Token) [][]Token {
+	for i, t := range tokens {
+		if t.Kind == TokParen && t.Value == "(" {
+			args, _ := parseFuncArgs(tokens, i)
+			return args
+		}
+	}
+	return nil
+}
+
 // assembleTypeName joins type tokens into a single type name string.
 func assembleTypeName(tokens []Token) string {
 	parts := make([]string, len(tokens))
@@ -338,18 +1303,145 @@ func mapCastType(pgType string) string {
 	return upper
 }
 
-// translateILIKE converts ILIKE to LIKE (SQLite LIKE is case-insensitive for ASCII by default).
+// translateILIKE converts ILIKE to a call of pg_ilike, which folds Unicode
+// case where SQLite's LIKE only folds ASCII:
+//
+//	name ILIKE 'caf%'                 -> pg_ilike(name, 'caf%')
+//	a || b NOT ILIKE $1 ESCAPE '!'    -> NOT pg_ilike(a || b, ?, '!')
+//
+// Arithmetic and || to the left of ILIKE bind tighter, so they are part of the
+// first argument. When an operand cannot be found ILIKE becomes LIKE.
 func translateILIKE(tokens []Token) []Token {
-	out := make([]Token, len(tokens))
-	copy(out, tokens)
-	for i := range out {
-		if out[i].Kind == TokKeyword && out[i].Value == "ILIKE" {
-			out[i] = Token{Kind: TokKeyword, Value: "LIKE", Raw: "LIKE"}
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "ILIKE" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		end := skipTriviaBack(out, len(out))
+		negated := end > 0 && out[end-1].Kind == TokKeyword && out[end-1].Value == "NOT"
+		if negated {
+			end = skipTriviaBack(out, end-1)
+		}
+		start := end - len(extractLeftExpr(out[:end]))
+		for start < end {
+			op := skipTriviaBack(out, start) - 1
+			if op < 1 || out[op].Kind != TokOperator || (!arithOps[out[op].Value] && out[op].Value != "||") {
+				break
+			}
+			prevEnd := skipTriviaBack(out, op)
+			prev := extractLeftExpr(out[:prevEnd])
+			if len(prev) == 0 {
+				break
+			}
+			start = prevEnd - len(prev)
+		}
+		patEnd := likePatternEnd(tokens, i+1)
+		if p := skipTrivia(tokens, i+1); p < len(tokens) && tokens[p].Kind == TokKeyword && (tokens[p].Value == "ANY" || tokens[p].Value == "ALL" || tokens[p].Value == "SOME") {
+			patEnd = i + 1 // ILIKE ANY (...) compares with each element
+		}
+		if start == end || patEnd == i+1 {
+			out = append(out, Token{Kind: TokKeyword, Value: "LIKE", Raw: "LIKE"})
+			continue
+		}
+
+		sep := []Token{{Kind: TokComma, Value: ",", Raw: ","}, {Kind: TokWhitespace, Value: " ", Raw: " "}}
+		call := Tokenize("pg_ilike(")
+		if negated {
+			call = Tokenize("NOT pg_ilike(")
 		}
+		call = append(call, out[start:end]...)
+		call = append(call, sep...)
+		call = append(call, trimTokenWhitespace(tokens[i+1:patEnd])...)
+		i = patEnd - 1
+		if e := skipTrivia(tokens, patEnd); e < len(tokens) && tokens[e].Kind == TokIdent && strings.EqualFold(tokens[e].Value, "ESCAPE") {
+			if c := skipTrivia(tokens, e+1); c < len(tokens) && tokens[c].Kind == TokString {
+				call = append(call, sep...)
+				call = append(call, tokens[c])
+				i = c
+			}
+		}
+		call = append(call, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		out = append(out[:start], call...)
 	}
 	return out
 }
 
+// translateLikeEscape gives LIKE PostgreSQL's default escape character. In PG a
+// backslash in a LIKE pattern escapes the next character (so '%\\%' matches a
+// literal backslash and 'a\%' a literal %), while SQLite's LIKE has no escape
+// character unless ESCAPE is given:
+//
+//	x LIKE pattern            -> x LIKE pattern ESCAPE '\'
+//	x LIKE pattern ESCAPE ''  -> x LIKE pattern  (PG: no escape character)
+//
+// An explicit ESCAPE clause is kept. Runs after ILIKE has become LIKE.
+func translateLikeEscape(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "LIKE" {
+			continue
+		}
+		end := likePatternEnd(tokens, i+1)
+		if end == i+1 {
+			continue
+		}
+		out = append(out, tokens[i+1:end]...)
+		i = end - 1
+
+		esc := skipTrivia(tokens, end)
+		if esc < len(tokens) && tokens[esc].Kind == TokIdent && strings.EqualFold(tokens[esc].Value, "ESCAPE") {
+			if lit := skipTrivia(tokens, esc+1); lit < len(tokens) && tokens[lit].Kind == TokString && tokens[lit].Value == "''" {
+				i = lit // ESCAPE '' disables escaping, which is SQLite's default
+			}
+			continue
+		}
+		out = append(out, Tokenize(` ESCAPE '\'`)...)
+	}
+	return out
+}
+
+// likePatternEnd returns the index just past the pattern expression that
+// starts at i, excluding trailing whitespace. The pattern ends at a comma, a
+// closing paren or a clause keyword such as AND, OR, THEN or ORDER, at depth 0.
+func likePatternEnd(tokens []Token, i int) int {
+	depth, cases := 0, 0
+	last := i
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Kind == TokWhitespace || t.Kind == TokComment:
+			continue
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			if depth == 0 {
+				return last
+			}
+			depth--
+		case depth > 0:
+		case t.Kind == TokComma || t.Kind == TokSemicolon:
+			return last
+		case t.Kind == TokIdent && strings.EqualFold(t.Value, "ESCAPE"):
+			return last
+		case t.Kind == TokKeyword:
+			switch {
+			case t.Value == "CASE":
+				cases++
+			case t.Value == "END" && cases > 0:
+				cases--
+			case cases > 0 || t.Value == "NULL" || isFuncCall(tokens, i):
+			default:
+				return last
+			}
+		}
+		last = i + 1
+	}
+	return last
+}
+
 // translateBooleans converts TRUE -> 1, FALSE -> 0 in non-DDL contexts.
 func translateBooleans(tokens []Token) []Token {
 	out := make([]Token, len(tokens))
@@ -0,0 +1,92 @@
+package pglike
+
+import "strings"
+
+// translateTsMatch converts the full-text match operator to a call of
+// pg_ts_match (see pgfuncs.go):
+//
+//	to_tsvector('simple', body) @@ to_tsquery('fat & cat') -> pg_ts_match(to_tsvector('simple', body), to_tsquery('fat & cat'))
+//
+// translateTextSearchFuncs later turns the to_tsvector call into
+// pg_to_tsvector and reduces the to_tsquery call to its text argument, so
+// pg_ts_match sees a tsvector and the query text.
+func translateTsMatch(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokOperator || tokens[i].Value != "@@" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		end := skipTriviaBack(out, len(out))
+		left := extractLeftExpr(out[:end])
+		r := skipTrivia(tokens, i+1)
+		right, rend := extractRightOperand(tokens, r)
+		if len(left) == 0 || len(right) == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		call := Tokenize("pg_ts_match(")
+		call = append(call, left...)
+		call = append(call,
+			Token{Kind: TokComma, Value: ",", Raw: ","},
+			Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+		)
+		call = append(call, right...)
+		call = append(call, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		out = append(out[:end-len(left)], call...)
+		i = rend
+	}
+	return out
+}
+
+// textSearchFuncs are the functions that build a tsvector or tsquery. A leading
+// configuration name such as 'english' is dropped from a call. to_tsvector
+// goes through pg_to_tsvector, which writes the text's words in PG's tsvector
+// form, so that a stored tsvector matches as the text it came from does. Text
+// is its own tsquery, so to_tsquery becomes its text argument, but
+// plainto_tsquery's text is not query syntax, so it goes through
+// pg_plainto_tsquery, which joins its words with &.
+var textSearchFuncs = map[string]bool{
+	"to_tsvector":     true,
+	"to_tsquery":      true,
+	"plainto_tsquery": true,
+}
+
+// translateTextSearchFuncs reduces the tsvector and tsquery constructors to
+// calls on their text argument, or to the text itself:
+//
+//	to_tsvector('english', title || ' ' || body) -> pg_to_tsvector(title || ' ' || body)
+//	to_tsquery($1)                               -> ?
+//	plainto_tsquery('english', $1)               -> pg_plainto_tsquery(?)
+func translateTextSearchFuncs(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind != TokIdent || !textSearchFuncs[strings.ToLower(t.Value)] || !isFuncCall(tokens, i) {
+			out = append(out, t)
+			continue
+		}
+		open := skipTrivia(tokens, i+1)
+		args, end := parseFuncArgs(tokens, open)
+		if len(args) == 0 || len(args) > 2 {
+			out = append(out, t)
+			continue
+		}
+		text := trimTokenWhitespace(args[len(args)-1])
+		if name := strings.ToLower(t.Value); name != "to_tsquery" {
+			out = append(out, Tokenize("pg_"+name+"(")...)
+			out = append(out, text...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		} else if len(text) == 1 {
+			out = append(out, text...)
+		} else {
+			out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
+			out = append(out, text...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		}
+		i = end
+	}
+	return out
+}
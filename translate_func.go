@@ -4,21 +4,130 @@ import "strings"
 
 // translateFunctions handles function-level translations:
 // NOW(), date_trunc(), EXTRACT(), string functions, etc.
-func translateFunctions(tokens []Token) []Token {
+func translateFunctions(tokens []Token, sc *scope) []Token {
+	tokens = translateFuncAliases(tokens)
+	tokens = translatePgTypeof(tokens, sc)
 	tokens = translateNow(tokens)
 	tokens = translateCurrentDatetime(tokens)
 	tokens = translateDateTrunc(tokens)
 	tokens = translateExtract(tokens)
 	tokens = translateStringFuncs(tokens)
-	tokens = translateAggFuncs(tokens)
+	tokens = translateAggFuncs(tokens, sc)
+	tokens = translateTextSearchFuncs(tokens)
 	return tokens
 }
 
-// translateNow converts NOW() -> datetime('now') (not in DEFAULT context, handled by DDL).
+// pgFuncAliases maps PG function names to the pg_* functions registered in
+// pgfuncs.go that implement their PostgreSQL semantics.
+var pgFuncAliases = map[string]string{
+	"reverse":      "pg_reverse",
+	"repeat":       "pg_repeat",
+	"chr":          "pg_chr",
+	"ascii":        "pg_ascii",
+	"greatest":     "pg_greatest",
+	"least":        "pg_least",
+	"translate":    "pg_translate",
+	"format":       "pg_format",
+	"starts_with":  "pg_starts_with",
+	"array_length": "pg_array_length",
+	"cardinality":  "pg_cardinality",
+	"random":       "pg_random",
+
+	"btrim": "trim", // the second argument is a set of characters in both
+
+	"substr":    "pg_substr",
+	"left":      "pg_left",
+	"right":     "pg_right",
+	"substring": "pg_substr",
+
+	"num_nulls":    "pg_num_nulls",
+	"num_nonnulls": "pg_num_nonnulls",
+
+	"digest": "pg_digest",
+	"encode": "pg_encode",
+	"decode": "pg_decode",
+
+	"clock_timestamp": "pg_clock_timestamp",
+
+	"regexp_matches": "pg_regexp_matches",
+
+	"mod": "pg_mod",
+
+	// SQLite has no whole-row references, so row_to_json only accepts a
+	// JSON object built explicitly, e.g. row_to_json(json_object('id', id)).
+	"to_json":     "json_quote",
+	"to_jsonb":    "json_quote",
+	"row_to_json": "json",
+}
+
+// translateFuncAliases renames calls to PG functions listed in pgFuncAliases,
+// e.g. reverse(name) -> pg_reverse(name). Bare identifiers (column names) are left alone.
+func translateFuncAliases(tokens []Token) []Token {
+	out := make([]Token, len(tokens))
+	copy(out, tokens)
+	for i := range out {
+		if out[i].Kind != TokIdent && out[i].Kind != TokKeyword {
+			continue
+		}
+		name := strings.ToLower(out[i].Value)
+		alias, ok := pgFuncAliases[name]
+		if !ok && mathFuncMissing(name) {
+			alias, ok = mathFallbacks[name].name, true
+		}
+		if !ok || !isFuncCall(out, i) {
+			continue
+		}
+		out[i] = Token{Kind: TokIdent, Value: alias, Raw: alias}
+	}
+	return out
+}
+
+// isFuncCall reports whether the token at i is followed (past whitespace) by an opening paren.
+func isFuncCall(tokens []Token, i int) bool {
+	j := i + 1
+	for j < len(tokens) && tokens[j].Kind == TokWhitespace {
+		j++
+	}
+	return j < len(tokens) && tokens[j].Kind == TokParen && tokens[j].Value == "("
+}
+
+// translatePgTypeof replaces pg_typeof(col) with the type col was declared
+// with, e.g. pg_typeof(active) -> 'boolean', when CREATE TABLE recorded it in
+// the catalog. Other arguments are left to the pg_typeof function, which can
+// only report the SQLite storage class.
+func translatePgTypeof(tokens []Token, sc *scope) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind == TokIdent && strings.EqualFold(t.Value, "pg_typeof") && isFuncCall(tokens, i) {
+			j := skipTrivia(tokens, i+1)
+			args, end := parseFuncArgs(tokens, j)
+			if len(args) == 1 {
+				if typ, ok := sc.refType(args[0]); ok {
+					out = append(out, Token{Kind: TokString, Value: "'" + typ + "'", Raw: "'" + typ + "'"})
+					i = end
+					continue
+				}
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// nowFuncs are the PG functions returning the transaction's start time. SQLite
+// keeps datetime('now') stable only within one statement, which is as close as
+// it gets.
+var nowFuncs = map[string]bool{
+	"now": true, "transaction_timestamp": true, "statement_timestamp": true,
+}
+
+// translateNow converts NOW(), transaction_timestamp() and statement_timestamp()
+// -> datetime('now') (not in DEFAULT context, handled by DDL).
 func translateNow(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
-		if tokens[i].Kind == TokKeyword && tokens[i].Value == "NOW" {
+		if (tokens[i].Kind == TokKeyword || tokens[i].Kind == TokIdent) && nowFuncs[strings.ToLower(tokens[i].Value)] {
 			// Look ahead for ()
 			j := i + 1
 			for j < len(tokens) && tokens[j].Kind == TokWhitespace {
@@ -157,7 +266,8 @@ func strftimeCall(format string, expr []Token) []Token {
 	return result
 }
 
-// translateExtract converts EXTRACT(field FROM expr) to CAST(strftime(fmt, expr) AS INTEGER).
+// translateExtract converts EXTRACT(field FROM expr) to the SQLite expression
+// built by extractField, e.g. CAST(strftime(fmt, expr) AS INTEGER).
 func translateExtract(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
@@ -205,17 +315,8 @@ func translateExtract(tokens []Token) []Token {
 							exprTokens = exprTokens[:len(exprTokens)-1]
 						}
 
-						fmt := extractFieldFormat(field)
-						if fmt != "" {
-							// CAST(strftime(fmt, expr) AS INTEGER)
-							out = append(out, Token{Kind: TokKeyword, Value: "CAST", Raw: "CAST"})
-							out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
-							out = append(out, strftimeCall("'"+fmt+"'", exprTokens)...)
-							out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-							out = append(out, Token{Kind: TokKeyword, Value: "AS", Raw: "AS"})
-							out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-							out = append(out, Token{Kind: TokKeyword, Value: "INTEGER", Raw: "INTEGER"})
-							out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+						if expr := extractField(field, exprTokens); expr != nil {
+							out = append(out, expr...)
 							i = m
 							continue
 						}
@@ -228,6 +329,46 @@ func translateExtract(tokens []Token) []Token {
 	return out
 }
 
+// extractField returns the SQLite expression computing an EXTRACT / date_part
+// field of expr, or nil if the field is not supported. Both use it, so they
+// support the same fields:
+//
+//   - strftime fields (extractFieldFormat): CAST(strftime(fmt, expr) AS INTEGER)
+//   - epoch: pg_epoch(expr), a REAL keeping fractional seconds, which also
+//     takes intervals (already held in seconds)
+//   - quarter: ((CAST(strftime('%m', expr) AS INTEGER) + 2) / 3)
+//   - decade, century: the year divided by 10, or rounded up to hundreds
+func extractField(field string, expr []Token) []Token {
+	castInt := func(format string) []Token {
+		out := []Token{{Kind: TokKeyword, Value: "CAST", Raw: "CAST"}, {Kind: TokParen, Value: "(", Raw: "("}}
+		out = append(out, strftimeCall("'"+format+"'", expr)...)
+		return append(out, Tokenize(" AS INTEGER)")...)
+	}
+	// wrap returns prefix + tokens + suffix as one parenthesized expression.
+	wrap := func(prefix string, tokens []Token, suffix string) []Token {
+		out := Tokenize("(" + prefix)
+		out = append(out, tokens...)
+		return append(out, Tokenize(suffix+")")...)
+	}
+
+	switch field {
+	case "epoch":
+		out := Tokenize("pg_epoch(")
+		out = append(out, expr...)
+		return append(out, Tokenize(")")...)
+	case "quarter":
+		return wrap("(", castInt("%m"), " + 2) / 3")
+	case "decade":
+		return wrap("", castInt("%Y"), " / 10")
+	case "century":
+		return wrap("(", castInt("%Y"), " + 99) / 100")
+	}
+	if format := extractFieldFormat(field); format != "" {
+		return castInt(format)
+	}
+	return nil
+}
+
 // extractFieldFormat returns the strftime format string for an EXTRACT field.
 func extractFieldFormat(field string) string {
 	switch field {
@@ -247,58 +388,96 @@ func extractFieldFormat(field string) string {
 		return "%w"
 	case "doy", "dayofyear":
 		return "%j"
+	case "week":
+		return "%V" // ISO 8601 week number
+	case "isodow":
+		return "%u" // Monday = 1 ... Sunday = 7
+	case "isoyear":
+		return "%G"
 	}
 	return ""
 }
 
 // translateStringFuncs handles string function rewrites.
 func translateStringFuncs(tokens []Token) []Token {
-	tokens = translateLeftRight(tokens)
 	tokens = translateConcat(tokens)
+	tokens = translateOverlay(tokens)
 	return tokens
 }
 
-// translateLeftRight converts left(str, n) -> substr(str, 1, n) and right(str, n) -> substr(str, -n).
-func translateLeftRight(tokens []Token) []Token {
+// translateOverlay converts PG's keyword form of overlay to a function call:
+//
+//	overlay(s PLACING r FROM n [FOR m]) -> pg_overlay(s, r, n [, m])
+//
+// Rewriting to substr concatenation would repeat s, which breaks $n parameters.
+func translateOverlay(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
-		if tokens[i].Kind == TokIdent || tokens[i].Kind == TokKeyword {
-			lower := strings.ToLower(tokens[i].Value)
-			if lower == "left" || lower == "right" {
-				j := i + 1
-				for j < len(tokens) && tokens[j].Kind == TokWhitespace {
-					j++
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "OVERLAY" {
+			out = append(out, tokens[i])
+			continue
+		}
+		j := skipTrivia(tokens, i+1)
+		if j >= len(tokens) || tokens[j].Kind != TokParen || tokens[j].Value != "(" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		// Find PLACING, FROM and FOR at the top level of the parentheses.
+		seps := map[string]int{}
+		end, depth := -1, 0
+		for k := j; k < len(tokens) && end < 0; k++ {
+			switch t := tokens[k]; {
+			case t.Kind == TokParen && t.Value == "(":
+				depth++
+			case t.Kind == TokParen && t.Value == ")":
+				if depth--; depth == 0 {
+					end = k
 				}
-				if j < len(tokens) && tokens[j].Kind == TokParen && tokens[j].Value == "(" {
-					args, endIdx := parseFuncArgs(tokens, j)
-					if len(args) == 2 {
-						out = append(out, Token{Kind: TokIdent, Value: "substr", Raw: "substr"})
-						out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
-						out = append(out, args[0]...)
-						out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
-						out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-						if lower == "left" {
-							out = append(out, Token{Kind: TokNumber, Value: "1", Raw: "1"})
-							out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","})
-							out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-							out = append(out, args[1]...)
-						} else {
-							out = append(out, Token{Kind: TokOperator, Value: "-", Raw: "-"})
-							out = append(out, args[1]...)
-						}
-						out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
-						i = endIdx
-						continue
-					}
+			case depth == 1 && t.Kind == TokKeyword && (t.Value == "PLACING" || t.Value == "FROM" || t.Value == "FOR"):
+				if _, dup := seps[t.Value]; !dup {
+					seps[t.Value] = k
 				}
 			}
 		}
-		out = append(out, tokens[i])
+		placing, okP := seps["PLACING"]
+		from, okF := seps["FROM"]
+		count, okC := seps["FOR"]
+		if end < 0 || !okP || !okF || placing > from || (okC && from > count) {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		args := [][]Token{tokens[j+1 : placing], tokens[placing+1 : from], tokens[from+1 : end]}
+		if okC {
+			args[2] = tokens[from+1 : count]
+			args = append(args, tokens[count+1:end])
+		}
+		out = append(out,
+			Token{Kind: TokIdent, Value: "pg_overlay", Raw: "pg_overlay"},
+			Token{Kind: TokParen, Value: "(", Raw: "("},
+		)
+		for ai, arg := range args {
+			if ai > 0 {
+				out = append(out,
+					Token{Kind: TokComma, Value: ",", Raw: ","},
+					Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+				)
+			}
+			out = append(out, trimTokenWhitespace(arg)...)
+		}
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		i = end
 	}
 	return out
 }
 
-// translateConcat converts concat(a, b, ...) to (COALESCE(a,”) || COALESCE(b,”) || ...).
+// translateConcat converts concat, which skips NULLs:
+//
+//	concat(a, b, ...) -> (COALESCE(a,'') || COALESCE(b,'') || ...)
+//	concat(a)         -> (COALESCE(a,'') || '')
+//
+// || makes text of numbers, as PG's concat does: concat(1, 2) is '12', not 3.
 func translateConcat(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
@@ -324,6 +503,9 @@ func translateConcat(tokens []Token) []Token {
 						out = append(out, Token{Kind: TokString, Value: "''", Raw: "''"})
 						out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
 					}
+					if len(args) == 1 {
+						out = append(out, Tokenize(" || ''")...)
+					}
 					out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
 					i = endIdx
 					continue
@@ -335,8 +517,32 @@ func translateConcat(tokens []Token) []Token {
 	return out
 }
 
+// splitAggOrderBy splits an aggregate argument at a top-level ORDER BY, as in
+// array_agg(x ORDER BY y), returning the expression and the ORDER BY clause
+// (with its leading whitespace), or nil when there is none. SQLite 3.44+
+// accepts the clause inside aggregate calls unchanged.
+func splitAggOrderBy(arg []Token) (expr, orderBy []Token) {
+	depth := 0
+	for i, t := range arg {
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokKeyword && t.Value == "ORDER":
+			start := i
+			for start > 0 && arg[start-1].Kind == TokWhitespace {
+				start--
+			}
+			return arg[:start], arg[start:]
+		}
+	}
+	return arg, nil
+}
+
 // translateAggFuncs converts string_agg -> group_concat, array_agg -> json_group_array.
-func translateAggFuncs(tokens []Token) []Token {
+// array_agg over a NUMERIC/DECIMAL column embeds the TEXT-stored values as JSON numbers.
+func translateAggFuncs(tokens []Token, sc *scope) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
 		if tokens[i].Kind == TokIdent {
@@ -347,9 +553,28 @@ func translateAggFuncs(tokens []Token) []Token {
 				continue
 			case "array_agg":
 				out = append(out, Token{Kind: TokIdent, Value: "json_group_array", Raw: "json_group_array"})
+				// NUMERIC/DECIMAL columns are stored as TEXT, which json_group_array
+				// would quote; embed them as JSON numbers instead.
+				j := i + 1
+				for j < len(tokens) && tokens[j].Kind == TokWhitespace {
+					j++
+				}
+				if j < len(tokens) && tokens[j].Kind == TokParen && tokens[j].Value == "(" {
+					args, endIdx := parseFuncArgs(tokens, j)
+					if len(args) == 1 {
+						expr, orderBy := splitAggOrderBy(args[0])
+						if typ, ok := sc.refType(expr); ok && typ == "numeric" {
+							arg := Reassemble(trimTokenWhitespace(expr))
+							out = append(out, Tokenize("(CASE WHEN json_valid("+arg+") THEN json("+arg+") ELSE "+arg+" END")...)
+							out = append(out, orderBy...)
+							out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+							i = endIdx
+						}
+					}
+				}
 				continue
 			case "date_part":
-				// date_part('field', expr) -> CAST(strftime(fmt, expr) AS INTEGER)
+				// date_part('field', expr) -> as EXTRACT(field FROM expr), via extractField
 				j := i + 1
 				for j < len(tokens) && tokens[j].Kind == TokWhitespace {
 					j++
@@ -358,16 +583,8 @@ func translateAggFuncs(tokens []Token) []Token {
 					args, endIdx := parseFuncArgs(tokens, j)
 					if len(args) == 2 {
 						field := strings.ToLower(strings.Trim(extractStringLiteral(args[0]), "'"))
-						fmt := extractFieldFormat(field)
-						if fmt != "" {
-							out = append(out, Token{Kind: TokKeyword, Value: "CAST", Raw: "CAST"})
-							out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
-							out = append(out, strftimeCall("'"+fmt+"'", args[1])...)
-							out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-							out = append(out, Token{Kind: TokKeyword, Value: "AS", Raw: "AS"})
-							out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
-							out = append(out, Token{Kind: TokKeyword, Value: "INTEGER", Raw: "INTEGER"})
-							out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+						if expr := extractField(field, trimTokenWhitespace(args[1])); expr != nil {
+							out = append(out, expr...)
 							i = endIdx
 							continue
 						}
@@ -384,7 +601,7 @@ func translateAggFuncs(tokens []Token) []Token {
 					if len(args) == 2 {
 						pgFmt := extractStringLiteral(args[1])
 						sqliteFmt, canMap := mapPGDateFormat(pgFmt)
-						if canMap && sqliteFmt != "" {
+						if canMap && sqliteFmt != "" && !isPGNumericFormat(strings.Trim(pgFmt, "'")) {
 							// Fast path: strftime
 							out = append(out, Token{Kind: TokIdent, Value: "strftime", Raw: "strftime"})
 							out = append(out, Token{Kind: TokParen, Value: "(", Raw: "("})
@@ -420,10 +637,17 @@ func parseFuncArgs(tokens []Token, openParen int) ([][]Token, int) {
 	var args [][]Token
 	var current []Token
 	depth := 0
+	brackets := 0 // ARRAY[...] elements are not arguments
 	i := openParen
 
 	for i < len(tokens) {
 		t := tokens[i]
+		if t.Kind == TokBracket && t.Value == "[" {
+			brackets++
+		}
+		if t.Kind == TokBracket && t.Value == "]" && brackets > 0 {
+			brackets--
+		}
 		if t.Kind == TokParen && t.Value == "(" {
 			depth++
 			if depth == 1 {
@@ -442,7 +666,7 @@ func parseFuncArgs(tokens []Token, openParen int) ([][]Token, int) {
 				return args, i
 			}
 		}
-		if t.Kind == TokComma && depth == 1 {
+		if t.Kind == TokComma && depth == 1 && brackets == 0 {
 			current = trimTokenWhitespace(current)
 			args = append(args, current)
 			current = nil
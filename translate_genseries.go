@@ -1,6 +1,9 @@
 package pglike
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 // translateGenerateSeries rewrites generate_series(start, stop[, step]) in FROM clause
 // to a WITH RECURSIVE CTE that SQLite can evaluate.
@@ -11,6 +14,15 @@ import "strings"
 //	SELECT start UNION ALL SELECT value + step FROM _gs WHERE value + step <= stop
 //
 // ) SELECT ... FROM _gs [AS alias]
+//
+// A negative literal step counts down with value + step >= stop; a non-literal
+// step picks the comparison with a CASE on its sign.
+//
+// When the step is an interval (INTERVAL '1 day', '1 day'::interval or '1 day'),
+// start and stop are treated as dates/timestamps and the series advances with
+// datetime(value, '+1 day') instead of value + step, one modifier per interval
+// field as intervalModifiers splits it. A zero step raises invalid_parameter_value
+// when the statement runs, through pg_series_step, as in PG.
 func translateGenerateSeries(tokens []Token) []Token {
 	// Find: FROM generate_series(...)
 	for i := 0; i < len(tokens); i++ {
@@ -44,16 +56,13 @@ func translateGenerateSeries(tokens []Token) []Token {
 			continue
 		}
 
-		startTokens := args[0]
-		stopTokens := args[1]
+		startStr := reassembleInline(args[0])
+		stopStr := reassembleInline(args[1])
 		stepStr := "1"
 		if len(args) == 3 {
-			stepStr = Reassemble(args[2])
+			stepStr = reassembleInline(args[2])
 		}
 
-		startStr := Reassemble(startTokens)
-		stopStr := Reassemble(stopTokens)
-
 		// Collect any alias after the closing paren: [AS alias]
 		aliasTokens := collectAlias(tokens, endParen+1)
 		aliasEnd := endParen
@@ -62,10 +71,53 @@ func translateGenerateSeries(tokens []Token) []Token {
 		}
 
 		// Build: WITH RECURSIVE _gs(value) AS (SELECT start UNION ALL SELECT value + step FROM _gs WHERE value + step <= stop)
-		cte := "WITH RECURSIVE _gs(value) AS (" +
-			"SELECT " + startStr +
-			" UNION ALL SELECT value + " + stepStr +
-			" FROM _gs WHERE value + " + stepStr + " <= " + stopStr + ") "
+		var cte string
+		if len(args) == 3 && isIntervalArg(args[2]) {
+			// Timestamp series: advance with datetime(value, 'N unit') and compare as datetimes.
+			text := intervalArgString(args[2])
+			next := "datetime(value)"
+			cmp := " <= "
+			guard := ""
+			iv, err := parseInterval(text)
+			mods, ok := intervalModifiers(text, false)
+			switch {
+			case err != nil || !ok:
+				// Unparseable: let pg_interval raise the error at run time.
+				guard = " WHERE pg_interval(" + quoteLiteral(text) + ") IS NOT NULL"
+			case iv.months == 0 && iv.days == 0 && iv.micros == 0:
+				guard = " WHERE pg_series_step(0)"
+			default:
+				next = "datetime(value, '" + strings.Join(mods, "', '") + "')"
+				// PG orders intervals by their length with 30-day months.
+				if (iv.months*30+iv.days)*86400e6+iv.micros < 0 {
+					cmp = " >= "
+				}
+			}
+			cte = "WITH RECURSIVE _gs(value) AS (" +
+				"SELECT datetime(" + startStr + ")" + guard +
+				" UNION ALL SELECT " + next +
+				" FROM _gs WHERE " + next + cmp + "datetime(" + stopStr + ")) "
+		} else {
+			// A literal step fixes the direction; otherwise decide on its sign at runtime.
+			next := "value + " + stepStr
+			if stepSign(args) == 0 {
+				next = "value + pg_series_step(" + stepStr + ")"
+			}
+			var cond string
+			switch stepSign(args) {
+			case 1:
+				cond = next + " <= " + stopStr
+			case -1:
+				cond = next + " >= " + stopStr
+			default:
+				cond = "CASE WHEN " + stepStr + " < 0 THEN " + next + " >= " + stopStr +
+					" ELSE " + next + " <= " + stopStr + " END"
+			}
+			cte = "WITH RECURSIVE _gs(value) AS (" +
+				"SELECT " + startStr +
+				" UNION ALL SELECT " + next +
+				" FROM _gs WHERE " + cond + ") "
+		}
 
 		cteTokens := Tokenize(cte)
 
@@ -94,6 +146,64 @@ func translateGenerateSeries(tokens []Token) []Token {
 	return tokens
 }
 
+// stepSign returns the sign of a literal generate_series step (1 when omitted),
+// or 0 when the step is zero or an expression whose sign is only known at
+// runtime.
+func stepSign(args [][]Token) int {
+	if len(args) < 3 {
+		return 1
+	}
+	step := trimTokenWhitespace(args[2])
+	sign := 1
+	if len(step) == 2 && step[0].Kind == TokOperator && (step[0].Value == "-" || step[0].Value == "+") {
+		if step[0].Value == "-" {
+			sign = -1
+		}
+		step = step[1:]
+	}
+	if len(step) != 1 || step[0].Kind != TokNumber {
+		return 0
+	}
+	if v, err := strconv.ParseFloat(step[0].Value, 64); err == nil && v == 0 {
+		return 0
+	}
+	return sign
+}
+
+// isIntervalArg reports whether a generate_series step is an interval:
+// INTERVAL '1 day', '1 day'::interval, or a bare '1 day' string literal.
+func isIntervalArg(arg []Token) bool {
+	arg = trimTokenWhitespace(arg)
+	if len(arg) == 0 {
+		return false
+	}
+	if arg[0].Kind == TokKeyword && arg[0].Value == "INTERVAL" {
+		return true
+	}
+	return arg[0].Kind == TokString
+}
+
+// intervalArgString extracts the interval text from a step argument, appending
+// the unit for the INTERVAL '1' DAY form.
+func intervalArgString(arg []Token) string {
+	var parts []string
+	for _, t := range trimTokenWhitespace(arg) {
+		switch {
+		case t.Kind == TokString:
+			parts = append(parts, strings.TrimSpace(strings.Trim(t.Value, "'")))
+		case t.Kind == TokKeyword || t.Kind == TokIdent:
+			if _, ok := lookupIntervalUnit(strings.ToLower(t.Value)); !ok {
+				continue
+			}
+			parts = append(parts, strings.ToLower(t.Value))
+		case t.Kind == TokOperator && t.Value == "::":
+			// '1 day'::interval: the cast target carries no information.
+			return strings.Join(parts, " ")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 // collectAlias collects optional [ws] AS [ws] alias tokens starting at pos.
 // Returns the collected tokens (including whitespace and AS).
 func collectAlias(tokens []Token, pos int) []Token {
@@ -1,9 +1,17 @@
 package pglike
 
-import "strings"
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
 
 // translateInterval rewrites expr +/- INTERVAL 'N unit' to datetime(expr, '+/-N unit').
 // Also handles the INTERVAL '1' DAY syntax (unit as separate keyword).
+// Standalone literals become their length in seconds:
+// INTERVAL '36 hours' -> 129600; see translateIntervalLiterals.
 func translateInterval(tokens []Token) []Token {
 	var out []Token
 	for i := 0; i < len(tokens); i++ {
@@ -56,25 +64,31 @@ func translateInterval(tokens []Token) []Token {
 					copy(lhsCopy, lhsTokens)
 					out = out[:lhsEnd-len(lhsTokens)]
 
-					// Build modifier string: +/-N unit
-					sign := "+"
-					if op == "-" {
-						sign = "-"
+					// Build one +/-N unit modifier per interval field.
+					modifiers, ok := intervalModifiers(intervalStr, op == "-")
+					if !ok {
+						sign := "+"
+						if op == "-" {
+							sign = "-"
+						}
+						modifiers = []string{sign + intervalStr}
 					}
-					modifier := sign + intervalStr
 
-					// Emit: datetime(lhs, 'modifier')
+					// Emit: datetime(lhs, 'modifier', ...)
 					out = append(out,
 						Token{Kind: TokIdent, Value: "datetime", Raw: "datetime"},
 						Token{Kind: TokParen, Value: "(", Raw: "("},
 					)
 					out = append(out, lhsCopy...)
-					out = append(out,
-						Token{Kind: TokComma, Value: ",", Raw: ","},
-						Token{Kind: TokWhitespace, Value: " ", Raw: " "},
-						Token{Kind: TokString, Value: "'" + modifier + "'", Raw: "'" + modifier + "'"},
-						Token{Kind: TokParen, Value: ")", Raw: ")"},
-					)
+					for _, m := range modifiers {
+						lit := "'" + m + "'"
+						out = append(out,
+							Token{Kind: TokComma, Value: ",", Raw: ","},
+							Token{Kind: TokWhitespace, Value: " ", Raw: " "},
+							Token{Kind: TokString, Value: lit, Raw: lit},
+						)
+					}
+					out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
 					i = endIdx
 					continue
 				}
@@ -82,9 +96,564 @@ func translateInterval(tokens []Token) []Token {
 		}
 		out = append(out, tokens[i])
 	}
+	return translateIntervalLiterals(out)
+}
+
+// intervalModifiers splits interval text such as '1 day 2 hours 30 minutes'
+// into one SQLite datetime modifier per field ('+1 day', '+2 hours',
+// '+30 minutes'), since datetime() accepts a single unit per modifier. The
+// signs are flipped when negate is set or the text ends in "ago". Units SQLite
+// lacks are converted: weeks to days, decades to years, and sub-second units
+// and HH:MM:SS fields to seconds. It returns false for text it can't split.
+func intervalModifiers(text string, negate bool) ([]string, bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if n := len(fields); n > 0 && fields[n-1] == "ago" {
+		negate = !negate
+		fields = fields[:n-1]
+	}
+	if len(fields) == 0 {
+		return nil, false
+	}
+	var months, days, times []string
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		neg := negate
+		if strings.HasPrefix(f, "-") {
+			neg = !neg
+		}
+		sign := "+"
+		if neg {
+			sign = "-"
+		}
+		f = strings.TrimLeft(f, "+-")
+		num := func(x float64) string { return strconv.FormatFloat(x, 'f', -1, 64) }
+		if strings.Contains(f, ":") {
+			micros, ok := parseIntervalTime(f)
+			if !ok {
+				return nil, false
+			}
+			times = append(times, sign+num(float64(micros)/1e6)+" seconds")
+			continue
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || i+1 >= len(fields) {
+			return nil, false
+		}
+		i++
+		unit := fields[i]
+		size, ok := lookupIntervalUnit(unit)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case isIntervalUnit(unit) && size.months != 0:
+			months = append(months, sign+f+" "+unit)
+		case isIntervalUnit(unit) && size.days != 0:
+			days = append(days, sign+f+" "+unit)
+		case isIntervalUnit(unit):
+			times = append(times, sign+f+" "+unit)
+		case size.months != 0 && size.months%12 == 0:
+			months = append(months, sign+num(v*float64(size.months/12))+" years")
+		case size.months != 0:
+			months = append(months, sign+num(v*float64(size.months))+" months")
+		case size.days != 0:
+			days = append(days, sign+num(v*float64(size.days))+" days")
+		default:
+			times = append(times, sign+num(v*float64(size.micros)/1e6)+" seconds")
+		}
+	}
+	// PG adds months, then days, then time; the order matters at month ends.
+	return append(append(months, days...), times...), true
+}
+
+// translateIntervalLiterals replaces standalone INTERVAL 'x' [unit] literals
+// with their length in seconds, as pg_interval would compute it, so that they
+// compare, sort and scale as numbers:
+//
+//	INTERVAL '1 hour' * 2             -> 3600 * 2
+//	INTERVAL '1 day' > '23 hours'     -> 86400 > ...
+//
+// A literal that doesn't parse becomes pg_interval('x'), so the error is
+// raised when the statement runs, as in PG.
+func translateIntervalLiterals(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		k := skipTrivia(tokens, i+1)
+		if tokens[i].Kind != TokKeyword || tokens[i].Value != "INTERVAL" || k >= len(tokens) || tokens[k].Kind != TokString {
+			out = append(out, tokens[i])
+			continue
+		}
+		text := strings.ReplaceAll(strings.Trim(tokens[k].Value, "'"), "''", "'")
+		end := k
+		if m := skipTrivia(tokens, k+1); m < len(tokens) && (tokens[m].Kind == TokKeyword || tokens[m].Kind == TokIdent) &&
+			isIntervalUnit(strings.ToLower(tokens[m].Value)) {
+			text += " " + strings.ToLower(tokens[m].Value)
+			end = m
+		}
+		lit := "'" + strings.ReplaceAll(text, "'", "''") + "'"
+		if iv, err := parseInterval(text); err == nil {
+			out = append(out, Tokenize(iv.literal())...)
+		} else {
+			out = append(out,
+				Token{Kind: TokIdent, Value: "pg_interval", Raw: "pg_interval"},
+				Token{Kind: TokParen, Value: "(", Raw: "("},
+				Token{Kind: TokString, Value: lit, Raw: lit},
+				Token{Kind: TokParen, Value: ")", Raw: ")"},
+			)
+		}
+		i = end
+	}
+	return out
+}
+
+// pgInterval is an interval held the way PG holds one: months, days and
+// microseconds are kept apart, since their lengths vary with the calendar.
+type pgInterval struct {
+	months, days, micros int64
+}
+
+// intervalUnits maps interval input units to their size in months, days or
+// microseconds (exactly one is non-zero).
+var intervalUnits = map[string]pgInterval{
+	"microsecond": {micros: 1}, "us": {micros: 1},
+	"millisecond": {micros: 1e3}, "ms": {micros: 1e3},
+	"second": {micros: 1e6}, "sec": {micros: 1e6}, "s": {micros: 1e6},
+	"minute": {micros: 60e6}, "min": {micros: 60e6}, "m": {micros: 60e6},
+	"hour": {micros: 3600e6}, "hr": {micros: 3600e6}, "h": {micros: 3600e6},
+	"day": {days: 1}, "d": {days: 1},
+	"week": {days: 7}, "w": {days: 7},
+	"month": {months: 1}, "mon": {months: 1},
+	"year": {months: 12}, "yr": {months: 12}, "y": {months: 12},
+	"decade": {months: 120}, "century": {months: 1200}, "millennium": {months: 12000},
+}
+
+// parseInterval parses PG interval input such as '1 year 2 mons', '1.5 days',
+// '-3 hours ago' or '1 day 02:30:00'. A trailing bare number counts as seconds.
+func parseInterval(s string) (pgInterval, error) {
+	var iv pgInterval
+	bad := fmt.Errorf("invalid input syntax for type interval: %q", s)
+	fields := strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "@")))
+	if len(fields) == 0 {
+		return iv, bad
+	}
+	ago := false
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if f == "ago" && i == len(fields)-1 {
+			ago = true
+			continue
+		}
+		if strings.Contains(f, ":") {
+			micros, ok := parseIntervalTime(f)
+			if !ok {
+				return iv, bad
+			}
+			iv.micros += micros
+			continue
+		}
+		// The unit may be attached ("3days") or the next field.
+		n := strings.IndexFunc(f, func(r rune) bool { return r >= 'a' && r <= 'z' })
+		num, unit := f, ""
+		if n > 0 {
+			num, unit = f[:n], f[n:]
+		} else if i+1 < len(fields) && fields[i+1] != "ago" && !strings.Contains(fields[i+1], ":") {
+			if _, err := strconv.ParseFloat(fields[i+1], 64); err != nil {
+				unit = fields[i+1]
+				i++
+			}
+		}
+		v, err := strconv.ParseFloat(num, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return iv, bad
+		}
+		if unit == "" {
+			unit = "second"
+		}
+		size, ok := lookupIntervalUnit(unit)
+		if !ok {
+			return iv, bad
+		}
+		iv.add(v, size)
+	}
+	if ago {
+		iv = pgInterval{-iv.months, -iv.days, -iv.micros}
+	}
+	return iv, nil
+}
+
+// lookupIntervalUnit finds an interval unit, accepting plurals ("days",
+// "mons", "centuries", "millennia").
+func lookupIntervalUnit(unit string) (pgInterval, bool) {
+	switch unit {
+	case "centuries":
+		unit = "century"
+	case "millennia", "millenniums":
+		unit = "millennium"
+	}
+	if size, ok := intervalUnits[unit]; ok {
+		return size, true
+	}
+	size, ok := intervalUnits[strings.TrimSuffix(unit, "s")]
+	return size, ok
+}
+
+// add adds v units of size, spilling fractional months into days (30 per
+// month) and fractional days into time, as PG does.
+func (iv *pgInterval) add(v float64, size pgInterval) {
+	switch {
+	case size.months != 0:
+		months := v * float64(size.months)
+		whole := math.Trunc(months)
+		iv.months += int64(whole)
+		v, size = (months-whole)*30, pgInterval{days: 1}
+		fallthrough
+	case size.days != 0:
+		days := v * float64(size.days)
+		whole := math.Trunc(days)
+		iv.days += int64(whole)
+		iv.micros += int64(math.Round((days - whole) * 86400e6))
+	default:
+		iv.micros += int64(math.Round(v * float64(size.micros)))
+	}
+}
+
+// parseIntervalTime parses a [-]H:MM[:SS[.frac]] field into microseconds.
+func parseIntervalTime(f string) (int64, bool) {
+	neg := strings.HasPrefix(f, "-")
+	parts := strings.Split(strings.TrimLeft(f, "+-"), ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	var micros int64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i < len(parts)-1 && strings.Contains(p, ".")) {
+			return 0, false
+		}
+		micros += int64(math.Round(v * [...]float64{3600e6, 60e6, 1e6}[i]))
+	}
+	if neg {
+		micros = -micros
+	}
+	return micros, true
+}
+
+// Seconds returns the length of iv in seconds, as PG's EXTRACT(EPOCH FROM iv)
+// computes it: a year is 365.25 days and any other month 30 days.
+func (iv pgInterval) Seconds() float64 {
+	days := float64(iv.months/12)*365.25 + float64(iv.months%12)*30 + float64(iv.days)
+	return days*86400 + float64(iv.micros)/1e6
+}
+
+// literal returns iv's length in seconds as an SQL number literal,
+// parenthesized when negative.
+func (iv pgInterval) literal() string {
+	s := strconv.FormatFloat(iv.Seconds(), 'f', -1, 64)
+	if strings.HasPrefix(s, "-") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// intervalColumns returns the columns of table the catalog records as INTERVAL.
+func intervalColumns(cat *catalog, table string) []string {
+	var cols []string
+	for _, col := range cat.tableColumns(table) {
+		if cat.columns[table+"."+col] == "interval" {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// translateIntervalWrites converts the values INSERT and UPDATE write to
+// INTERVAL columns to seconds with pg_interval, so that such a column holds
+// numbers whether it is given an interval literal, text or a parameter:
+//
+//	INSERT INTO t (d) VALUES ('1 day'), (?) -> INSERT INTO t (d) VALUES (pg_interval('1 day')), (pg_interval(?))
+//	UPDATE t SET d = $1 WHERE id = 1       -> UPDATE t SET d = pg_interval($1) WHERE id = 1
+//
+// ON CONFLICT ... DO UPDATE SET is handled as UPDATE is. An INSERT without a
+// column list is given one as in translateSequenceDefaults. DEFAULT and NULL
+// are left as they are, and so is INSERT ... SELECT, whose select list must
+// give intervals as seconds already, e.g. with ::interval.
+func translateIntervalWrites(tokens []Token, sc *scope) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword {
+		return tokens
+	}
+	switch tokens[i].Value {
+	case "INSERT":
+		table, j, ok := insertTarget(tokens)
+		if cols := intervalColumns(sc.cat, table); ok && len(cols) > 0 {
+			tokens = intervalInsertValues(insertColumnList(tokens, j, table, sc), cols)
+			return intervalAssignments(tokens, cols)
+		}
+	case "UPDATE":
+		j := skipTrivia(tokens, i+1)
+		if k, ok := peekKeyword(tokens, j, "ONLY"); ok {
+			j = skipTrivia(tokens, k+1)
+		}
+		table := ""
+		for ; j < len(tokens) && (tokens[j].Kind == TokIdent || tokens[j].Kind == TokDot); j++ {
+			if tokens[j].Kind == TokIdent {
+				table = identName(tokens[j])
+			}
+		}
+		if cols := intervalColumns(sc.cat, table); len(cols) > 0 {
+			return intervalAssignments(tokens, cols)
+		}
+	}
+	return tokens
+}
+
+// intervalInsertValues wraps the VALUES items for the INTERVAL columns cols
+// in pg_interval.
+func intervalInsertValues(tokens []Token, cols []string) []Token {
+	open, names, rows, end, ok := parseInsertValues(tokens)
+	if !ok {
+		return tokens
+	}
+	changed := false
+	for c, name := range names {
+		name = trimTokenWhitespace(name)
+		if len(name) != 1 || !slices.Contains(cols, identName(name[0])) {
+			continue
+		}
+		for _, row := range rows {
+			row[c] = intervalValue(row[c])
+		}
+		changed = true
+	}
+	if !changed {
+		return tokens
+	}
+	out := append([]Token(nil), tokens[:open]...)
+	out = append(out, insertValuesList(names, rows)...)
+	return append(out, tokens[end+1:]...)
+}
+
+// intervalAssignments wraps the values SET assigns to the INTERVAL columns
+// cols in pg_interval.
+func intervalAssignments(tokens []Token, cols []string) []Token {
+	var out []Token
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		out = append(out, t)
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && t.Kind == TokSemicolon:
+			return append(out, tokens[i+1:]...)
+		}
+		if depth != 0 || t.Kind != TokKeyword || t.Value != "SET" {
+			continue
+		}
+
+		// col = value [, ...], ending at WHERE, FROM or RETURNING.
+		for {
+			col := skipTrivia(tokens, i+1)
+			eq := skipTrivia(tokens, col+1)
+			if eq >= len(tokens) || tokens[col].Kind != TokIdent || tokens[eq].Kind != TokOperator || tokens[eq].Value != "=" {
+				break
+			}
+			end, d := eq+1, 0
+		scan:
+			for ; end < len(tokens); end++ {
+				switch v := tokens[end]; {
+				case v.Kind == TokParen && v.Value == "(":
+					d++
+				case v.Kind == TokParen && v.Value == ")":
+					if d == 0 {
+						break scan
+					}
+					d--
+				case d != 0:
+				case v.Kind == TokComma, v.Kind == TokSemicolon:
+					break scan
+				case v.Kind == TokKeyword && (v.Value == "WHERE" || v.Value == "FROM" || v.Value == "RETURNING"):
+					break scan
+				}
+			}
+			out = append(out, tokens[i+1:eq+1]...)
+			if slices.Contains(cols, identName(tokens[col])) {
+				out = append(out, intervalValue(tokens[eq+1:end])...)
+			} else {
+				out = append(out, tokens[eq+1:end]...)
+			}
+			i = end - 1
+			if end >= len(tokens) || tokens[end].Kind != TokComma {
+				break
+			}
+			out = append(out, tokens[end])
+			i = end
+		}
+	}
+	return out
+}
+
+// intervalValue wraps the value v, written to an INTERVAL column, in
+// pg_interval, keeping the whitespace around it. DEFAULT and NULL are
+// returned as they are.
+func intervalValue(v []Token) []Token {
+	start, end := skipTrivia(v, 0), skipTriviaBack(v, len(v))
+	if start >= end || end-start == 1 && v[start].Kind == TokKeyword && (v[start].Value == "DEFAULT" || v[start].Value == "NULL") {
+		return v
+	}
+	out := append([]Token(nil), v[:start]...)
+	out = append(out,
+		Token{Kind: TokIdent, Value: "pg_interval", Raw: "pg_interval"},
+		Token{Kind: TokParen, Value: "(", Raw: "("})
+	out = append(out, v[start:end]...)
+	out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	return append(out, v[end:]...)
+}
+
+// translateTimestampDiff rewrites the difference of two timestamps, which is
+// an interval in PG, to pg_timestamp_diff(a, b):
+//
+//	now() - created_at -> pg_timestamp_diff(now(), created_at)
+//
+// Both operands must be recognizably timestamps (see isTimestampOperand);
+// anything else is left as numeric subtraction. Date - date, an integer in
+// PG, is not affected.
+func translateTimestampDiff(tokens []Token, sc *scope) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TokOperator || tokens[i].Value != "-" {
+			out = append(out, tokens[i])
+			continue
+		}
+		end := skipTriviaBack(out, len(out))
+		start, ok := timestampOperandStart(out, end, sc)
+		if !ok {
+			out = append(out, tokens[i])
+			continue
+		}
+		r := skipTrivia(tokens, i+1)
+		rend, ok := timestampOperandEnd(tokens, r, sc)
+		if !ok {
+			out = append(out, tokens[i])
+			continue
+		}
+		left := append([]Token(nil), out[start:end]...)
+		out = append(out[:start], Token{Kind: TokIdent, Value: "pg_timestamp_diff", Raw: "pg_timestamp_diff"},
+			Token{Kind: TokParen, Value: "(", Raw: "("})
+		out = append(out, stripTimestampKeyword(left)...)
+		out = append(out, Token{Kind: TokComma, Value: ",", Raw: ","}, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+		out = append(out, stripTimestampKeyword(tokens[r:rend+1])...)
+		out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+		i = rend
+	}
 	return out
 }
 
+// timestampOperandStart returns where the timestamp operand ending at end in
+// out begins, if it is one.
+func timestampOperandStart(out []Token, end int, sc *scope) (int, bool) {
+	if end == 0 {
+		return 0, false
+	}
+	last := out[end-1]
+	// Typed literal: TIMESTAMP '...'
+	if last.Kind == TokString {
+		p := skipTriviaBack(out, end-1)
+		if p > 0 && isTimestampTypeWord(out[p-1]) {
+			return p - 1, true
+		}
+		return 0, false
+	}
+	// Cast: expr::timestamp [with[out] time zone]
+	if isTimestampTypeWord(last) || last.Kind == TokKeyword && last.Value == "ZONE" {
+		p := end
+		for p > 0 && (out[p-1].Kind == TokWhitespace || out[p-1].Kind == TokKeyword &&
+			(isTimestampTypeWord(out[p-1]) || out[p-1].Value == "WITH" || out[p-1].Value == "WITHOUT" ||
+				out[p-1].Value == "TIME" || out[p-1].Value == "ZONE")) {
+			p--
+		}
+		if p == 0 || out[p-1].Kind != TokOperator || out[p-1].Value != "::" {
+			return 0, false
+		}
+		expr := extractLeftExpr(out[:p-1])
+		return p - 1 - len(expr), len(expr) > 0
+	}
+	start := end - len(extractLeftExpr(out[:end]))
+	for start >= 2 && out[start-1].Kind == TokDot && out[start-2].Kind == TokIdent {
+		start -= 2 // qualified name: t.col
+	}
+	return start, isTimestampOperand(out[start:end], sc)
+}
+
+// timestampOperandEnd returns the index of the last token of the timestamp
+// operand starting at start, if it is one.
+func timestampOperandEnd(tokens []Token, start int, sc *scope) (int, bool) {
+	if start >= len(tokens) {
+		return 0, false
+	}
+	if isTimestampTypeWord(tokens[start]) {
+		s := skipTrivia(tokens, start+1)
+		return s, s < len(tokens) && tokens[s].Kind == TokString
+	}
+	operand, end := extractRightOperand(tokens, start)
+	if len(operand) == 0 || operand[0].Kind == TokOperator {
+		return 0, false
+	}
+	if c := skipTrivia(tokens, end+1); c < len(tokens) && tokens[c].Kind == TokOperator && tokens[c].Value == "::" {
+		typ, tend := extractTypeName(tokens, c+1)
+		return tend, len(typ) > 0 && isTimestampTypeWord(typ[0])
+	}
+	return end, isTimestampOperand(operand, sc)
+}
+
+// isTimestampTypeWord reports whether t is the TIMESTAMP or TIMESTAMPTZ keyword.
+func isTimestampTypeWord(t Token) bool {
+	return t.Kind == TokKeyword && (t.Value == "TIMESTAMP" || t.Value == "TIMESTAMPTZ")
+}
+
+// timestampFuncs are the functions returning the current timestamp, and
+// datetime() as produced by interval arithmetic.
+var timestampFuncs = map[string]bool{
+	"now": true, "datetime": true, "statement_timestamp": true,
+	"clock_timestamp": true, "transaction_timestamp": true,
+}
+
+// isTimestampOperand reports whether the operand is known to be a timestamp: a
+// call to one of timestampFuncs, CURRENT_TIMESTAMP, a column declared as a
+// timestamp, or such an operand in parentheses.
+func isTimestampOperand(op []Token, sc *scope) bool {
+	op = trimTokenWhitespace(op)
+	if len(op) == 0 {
+		return false
+	}
+	first, last := op[0], op[len(op)-1]
+	switch {
+	case len(op) == 1 && first.Kind == TokKeyword && first.Value == "CURRENT_TIMESTAMP":
+		return true
+	case len(op) > 1 && first.Kind == TokParen && first.Value == "(" && skipParenGroup(op, 0) == len(op)-1:
+		return isTimestampOperand(op[1:len(op)-1], sc)
+	case (first.Kind == TokIdent || first.Kind == TokKeyword) && last.Kind == TokParen && isFuncCall(op, 0):
+		return timestampFuncs[strings.ToLower(first.Value)]
+	case last.Kind == TokIdent:
+		typ, ok := sc.refType(op)
+		return ok && strings.HasPrefix(typ, "timestamp ")
+	}
+	return false
+}
+
+// stripTimestampKeyword turns a typed literal TIMESTAMP '...' into its string;
+// other operands are returned unchanged.
+func stripTimestampKeyword(op []Token) []Token {
+	if len(op) > 0 && isTimestampTypeWord(op[0]) {
+		if s := skipTrivia(op, 1); s == len(op)-1 && op[s].Kind == TokString {
+			return op[s:]
+		}
+	}
+	return op
+}
+
 // isIntervalUnit checks if a keyword is a valid interval unit.
 func isIntervalUnit(s string) bool {
 	switch s {
@@ -0,0 +1,418 @@
+package pglike
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonEachFuncs lists the PG key/value set-returning functions. The value is
+// true for the _text variants, whose value column is text rather than JSON.
+var jsonEachFuncs = map[string]bool{
+	"json_each":       false,
+	"jsonb_each":      false,
+	"json_each_text":  true,
+	"jsonb_each_text": true,
+}
+
+// jsonElementsFuncs lists the PG functions expanding a JSON array into rows,
+// true for the _text variants as in jsonEachFuncs.
+var jsonElementsFuncs = map[string]bool{
+	"json_array_elements":       false,
+	"jsonb_array_elements":      false,
+	"json_array_elements_text":  true,
+	"jsonb_array_elements_text": true,
+}
+
+// jsonKeysFuncs lists the PG functions returning the keys of a JSON object.
+var jsonKeysFuncs = map[string]bool{
+	"json_object_keys":  true,
+	"jsonb_object_keys": true,
+}
+
+// jsonEachValue renders SQLite json_each's value column the way PG does:
+// JSON text for json(b)_each, plain text for the _text variants.
+const (
+	jsonEachValue     = "CASE type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' WHEN 'null' THEN 'null' ELSE json_quote(value) END"
+	jsonEachTextValue = "CASE type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE value END"
+)
+
+// translateJSONEach rewrites the JSON set-returning functions json(b)_each[_text],
+// json(b)_array_elements[_text] and json(b)_object_keys in FROM clauses.
+//
+// Directly after FROM the call is wrapped so it yields exactly PG's key/value columns:
+//
+//	FROM jsonb_each(data) AS e -> FROM (SELECT key, <value> AS value FROM json_each(data)) AS e
+//
+// json(b)_array_elements[_text] yields just the value column, which PG also
+// lets the alias name, so an alias adds a copy of it under that name:
+//
+//	FROM jsonb_array_elements(arr) AS e -> FROM (SELECT <value> AS value, <value> AS e FROM json_each(arr)) AS e
+//
+// json(b)_object_keys yields one column, named after the alias as in PG:
+//
+//	FROM jsonb_object_keys(data) AS k -> FROM (SELECT key AS k FROM json_each(data)) AS k
+//
+// After a comma or JOIN the argument usually references an earlier table, which a
+// FROM subquery cannot see, so the call is only renamed to SQLite's json_each
+// (its key and value columns are still available by name). For the
+// single-column functions, other references to the bare alias then become its
+// column:
+//
+//	SELECT x FROM d, json_array_elements(d.j) x -> SELECT <x.value> FROM d, json_each(d.j) x
+func translateJSONEach(tokens []Token) []Token {
+	var out []Token
+	// Bare aliases of single-column calls after a comma or JOIN, mapped to
+	// their column, with the index in out where each alias is declared.
+	aliasCols := map[string]string{}
+	aliasDecl := map[int]bool{}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		name := strings.ToLower(t.Value)
+		asText, each := jsonEachFuncs[name]
+		elemText, elems := jsonElementsFuncs[name]
+		keys := jsonKeysFuncs[name]
+		if t.Kind != TokIdent || !(each || elems || keys) || !isFuncCall(tokens, i) {
+			out = append(out, t)
+			continue
+		}
+
+		prev := len(out) - 1
+		for prev >= 0 && out[prev].Kind == TokWhitespace {
+			prev--
+		}
+		if prev < 0 {
+			out = append(out, t)
+			continue
+		}
+		p := out[prev]
+		switch {
+		case p.Kind == TokKeyword && p.Value == "FROM":
+			k := i + 1
+			for tokens[k].Kind == TokWhitespace {
+				k++
+			}
+			args, endParen := parseFuncArgs(tokens, k)
+			if len(args) != 1 {
+				out = append(out, t)
+				continue
+			}
+			alias := collectAlias(tokens, endParen+1)
+			value := jsonEachValue
+			if asText || elemText {
+				value = jsonEachTextValue
+			}
+			var cols string
+			switch {
+			case keys:
+				cols = "key AS " + name
+				if len(alias) > 0 {
+					cols = "key AS " + alias[len(alias)-1].Raw
+				}
+			case elems:
+				cols = value + " AS value"
+				if len(alias) > 0 && !strings.EqualFold(alias[len(alias)-1].Value, "value") {
+					cols += ", " + value + " AS " + alias[len(alias)-1].Raw
+				}
+			default:
+				cols = "key, " + value + " AS value"
+			}
+			sub := "(SELECT " + cols + " FROM json_each(" + reassembleInline(args[0]) + "))"
+			out = append(out, Tokenize(sub)...)
+			// PG names the relation after the function when no alias is given.
+			if len(alias) == 0 {
+				out = append(out, Tokenize(" AS "+name)...)
+			}
+			i = endParen
+		case p.Kind == TokComma || (p.Kind == TokKeyword && p.Value == "JOIN"):
+			out = append(out, Token{Kind: TokIdent, Value: "json_each", Raw: "json_each"})
+			if !elems && !keys {
+				continue
+			}
+			endParen := skipParenGroup(tokens, skipTrivia(tokens, i+1))
+			alias := collectAlias(tokens, endParen+1)
+			if len(alias) == 0 {
+				continue
+			}
+			name := alias[len(alias)-1]
+			out = append(out, tokens[i+1:endParen+1+len(alias)]...)
+			aliasDecl[len(out)-1] = true
+			i = endParen + len(alias)
+			switch {
+			case keys:
+				aliasCols[strings.ToLower(name.Value)] = name.Raw + ".key"
+			case elemText:
+				aliasCols[strings.ToLower(name.Value)] = jsonEachColumnValue(name.Raw, jsonEachTextValue)
+			default:
+				aliasCols[strings.ToLower(name.Value)] = jsonEachColumnValue(name.Raw, jsonEachValue)
+			}
+		default:
+			out = append(out, t)
+		}
+	}
+	if len(aliasCols) == 0 {
+		return out
+	}
+	var res []Token
+	for i, t := range out {
+		col, ok := aliasCols[strings.ToLower(t.Value)]
+		if !ok || t.Kind != TokIdent || aliasDecl[i] ||
+			(i > 0 && out[i-1].Kind == TokDot) || (i+1 < len(out) && out[i+1].Kind == TokDot) {
+			res = append(res, t)
+			continue
+		}
+		res = append(res, Tokenize(col)...)
+	}
+	return res
+}
+
+// jsonEachColumnValue qualifies the type and value columns of a json_each
+// value expression with alias.
+func jsonEachColumnValue(alias, value string) string {
+	value = strings.ReplaceAll(value, "CASE type", "CASE "+alias+".type")
+	value = strings.ReplaceAll(value, "json_quote(value)", "json_quote("+alias+".value)")
+	return strings.ReplaceAll(value, "ELSE value", "ELSE "+alias+".value")
+}
+
+// translateToJSONBooleans converts to_json and to_jsonb of a boolean to JSON
+// true or false. SQLite has no boolean type, so json_quote, their usual
+// target, would give 1 or 0:
+//
+//	to_jsonb(TRUE)     -> json('true')
+//	to_json(a > b)     -> json(CASE WHEN (a > b) THEN 'true' WHEN NOT (a > b) THEN 'false' END)
+//
+// An argument is taken to be boolean when it is TRUE or FALSE, a ::boolean
+// cast, a comparison or logical expression, or a column declared BOOLEAN.
+func translateToJSONBooleans(tokens []Token, sc *scope) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		name := strings.ToLower(t.Value)
+		if t.Kind != TokIdent || (name != "to_json" && name != "to_jsonb") || !isFuncCall(tokens, i) {
+			out = append(out, t)
+			continue
+		}
+		args, end := parseFuncArgs(tokens, skipTrivia(tokens, i+1))
+		if len(args) != 1 {
+			out = append(out, t)
+			continue
+		}
+		arg := trimTokenWhitespace(args[0])
+		switch {
+		case len(arg) == 1 && arg[0].Kind == TokKeyword && (arg[0].Value == "TRUE" || arg[0].Value == "FALSE"):
+			out = append(out, Tokenize("json('"+strings.ToLower(arg[0].Value)+"')")...)
+		case isBooleanExpr(arg, sc):
+			expr := "(" + reassembleInline(arg) + ")"
+			out = append(out, Tokenize("json(CASE WHEN "+expr+" THEN 'true' WHEN NOT "+expr+" THEN 'false' END)")...)
+		default:
+			out = append(out, t)
+			continue
+		}
+		i = end
+	}
+	return out
+}
+
+// isBooleanExpr reports whether expr is known to be boolean: a ::boolean cast,
+// a comparison or logical expression at the top level, or a column declared
+// BOOLEAN.
+func isBooleanExpr(expr []Token, sc *scope) bool {
+	if n := len(expr); n >= 2 && expr[n-2].Kind == TokOperator && expr[n-2].Value == "::" &&
+		expr[n-1].Kind == TokKeyword && (expr[n-1].Value == "BOOLEAN" || expr[n-1].Value == "BOOL") {
+		return true
+	}
+	depth := 0
+	for _, t := range expr {
+		switch {
+		case t.Kind == TokParen && t.Value == "(", t.Kind == TokKeyword && t.Value == "CASE":
+			depth++
+		case t.Kind == TokParen && t.Value == ")", t.Kind == TokKeyword && t.Value == "END":
+			depth--
+		case depth > 0:
+		case t.Kind == TokOperator:
+			switch t.Value {
+			case "=", "<", ">", "<=", ">=", "<>", "!=":
+				return true
+			}
+		case t.Kind == TokKeyword:
+			switch t.Value {
+			case "AND", "OR", "NOT", "IS", "IN", "LIKE", "ILIKE", "BETWEEN", "EXISTS":
+				return true
+			}
+		}
+	}
+	typ, ok := sc.refType(expr)
+	return ok && typ == "boolean"
+}
+
+// jsonEditFuncs maps the PG JSON editing functions to SQLite's equivalents.
+var jsonEditFuncs = map[string]string{
+	"jsonb_set":    "json_set",
+	"json_set":     "json_set",
+	"jsonb_insert": "pg_jsonb_insert",
+}
+
+// translateJSONSet rewrites PG's JSON editing functions to SQLite's:
+//
+//	jsonb_set(target, '{a,b}', value [, create_missing])  -> json_set(target, '$.a.b', json(value))
+//	jsonb_insert(target, '{a,0}', value [, insert_after]) -> json(pg_jsonb_insert(target, '{a,0}', json(value), insert_after))
+//
+// create_missing false selects json_replace instead. A path written as an array
+// literal or ARRAY[...] of strings is converted here, any other path at run
+// time by pg_json_path. json_set is also SQLite's own function, so it is only
+// rewritten when its path is a PG array literal rather than a JSONPath.
+//
+// SQLite's json_insert can neither shift array elements nor fail on an
+// existing key, so jsonb_insert goes to pg_jsonb_insert, which takes the PG
+// path itself; an ARRAY[...] path is passed as json_array(...).
+func translateJSONSet(tokens []Token) []Token {
+	var out []Token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		name := strings.ToLower(t.Value)
+		fn, ok := jsonEditFuncs[name]
+		if t.Kind != TokIdent || !ok || !isFuncCall(tokens, i) {
+			out = append(out, t)
+			continue
+		}
+		open := skipTrivia(tokens, i+1)
+		args, end := parseFuncArgs(tokens, open)
+		if len(args) < 3 || len(args) > 4 {
+			out = append(out, t)
+			continue
+		}
+		if name == "jsonb_insert" {
+			for a := range args {
+				args[a] = translateJSONSet(trimTokenWhitespace(args[a]))
+			}
+			path := reassembleInline(args[1])
+			if p := args[1]; len(p) > 0 && p[0].Kind == TokKeyword && p[0].Value == "ARRAY" {
+				if k := skipTrivia(p, 1); k < len(p) && p[k].Kind == TokBracket && p[k].Value == "[" &&
+					p[len(p)-1].Kind == TokBracket && p[len(p)-1].Value == "]" {
+					path = "json_array(" + reassembleInline(p[k+1:len(p)-1]) + ")"
+				}
+			}
+			after := "0"
+			if len(args) == 4 {
+				after = reassembleInline(args[3])
+			}
+			out = append(out, Tokenize("json("+fn+"("+reassembleInline(args[0])+", "+path+
+				", json("+reassembleInline(args[2])+"), "+after+"))")...)
+			i = end
+			continue
+		}
+		path, literal := jsonPathArg(args[1])
+		if name == "json_set" && !literal {
+			out = append(out, t)
+			continue
+		}
+		if len(args) == 4 {
+			flag, ok := boolLiteral(args[3])
+			switch {
+			case !ok:
+				// A computed flag has no SQLite equivalent.
+				out = append(out, t)
+				continue
+			case !flag:
+				fn = "json_replace"
+			}
+		}
+		for a := range args[:3] {
+			args[a] = translateJSONSet(trimTokenWhitespace(args[a]))
+		}
+		s := fn + "(" + reassembleInline(args[0]) + ", "
+		if literal {
+			s += "'" + strings.ReplaceAll(path, "'", "''") + "'"
+		} else {
+			s += "pg_json_path(" + reassembleInline(args[1]) + ")"
+		}
+		out = append(out, Tokenize(s+", json("+reassembleInline(args[2])+"))")...)
+		i = end
+	}
+	return out
+}
+
+// jsonPathArg converts a PG path argument given as a literal, '{a,b}' or
+// '{a,b}'::text[], or as ARRAY['a', 'b'], to a SQLite JSONPath.
+func jsonPathArg(arg []Token) (string, bool) {
+	arg = trimTokenWhitespace(arg)
+	if len(arg) == 0 {
+		return "", false
+	}
+	if arg[0].Kind == TokString {
+		if len(arg) > 1 && (arg[1].Kind != TokOperator || arg[1].Value != "::") {
+			return "", false
+		}
+		elems, err := parseTextArray(strings.ReplaceAll(strings.Trim(arg[0].Value, "'"), "''", "'"))
+		if err != nil {
+			return "", false
+		}
+		return pgJSONPath(elems), true
+	}
+	if arg[0].Kind != TokKeyword || arg[0].Value != "ARRAY" {
+		return "", false
+	}
+	var elems []string
+	for _, tok := range arg[1:] {
+		switch {
+		case tok.Kind == TokString:
+			elems = append(elems, strings.ReplaceAll(strings.Trim(tok.Value, "'"), "''", "'"))
+		case tok.Kind == TokWhitespace || tok.Kind == TokComma,
+			tok.Kind == TokBracket:
+		default:
+			return "", false
+		}
+	}
+	return pgJSONPath(elems), true
+}
+
+// boolLiteral returns the value of a TRUE or FALSE argument.
+func boolLiteral(arg []Token) (bool, bool) {
+	arg = trimTokenWhitespace(arg)
+	if len(arg) != 1 || arg[0].Kind != TokKeyword {
+		return false, false
+	}
+	switch arg[0].Value {
+	case "TRUE":
+		return true, true
+	case "FALSE":
+		return false, true
+	}
+	return false, false
+}
+
+// pgJSONPath converts the elements of a PG JSON path to a SQLite JSONPath.
+// Integers become array subscripts, negative ones counting from the end;
+// other elements are object keys.
+func pgJSONPath(elems []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, e := range elems {
+		if n, err := strconv.Atoi(e); err == nil {
+			if n < 0 {
+				fmt.Fprintf(&b, "[#%d]", n)
+			} else {
+				fmt.Fprintf(&b, "[%d]", n)
+			}
+			continue
+		}
+		b.WriteString(".")
+		if isPlainJSONKey(e) {
+			b.WriteString(e)
+		} else {
+			b.WriteString(`"` + e + `"`)
+		}
+	}
+	return b.String()
+}
+
+// isPlainJSONKey reports whether key can be written unquoted in a JSONPath.
+func isPlainJSONKey(key string) bool {
+	for i, r := range key {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return key != ""
+}
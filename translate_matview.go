@@ -0,0 +1,229 @@
+package pglike
+
+import (
+	"strings"
+	"sync"
+)
+
+// matViews records how CREATE MATERIALIZED VIEW is translated: with snapshots
+// off (the default) views become plain views, with snapshots on they become
+// tables. The setting is process-wide; each view's query and how it was created
+// are kept in its database's catalog.
+var matViews = struct {
+	mu        sync.RWMutex
+	snapshots bool
+}{}
+
+// SetMaterializedViewSnapshots selects how CREATE MATERIALIZED VIEW is
+// translated. By default it creates a plain view, which is always current, and
+// REFRESH MATERIALIZED VIEW does nothing. When enabled, it creates a table
+// holding a snapshot of the query, and REFRESH MATERIALIZED VIEW replaces its
+// rows with the query's current result. The setting applies to views created
+// after it; REFRESH and DROP go by how the view was created.
+func SetMaterializedViewSnapshots(enabled bool) {
+	matViews.mu.Lock()
+	matViews.snapshots = enabled
+	matViews.mu.Unlock()
+	// Cached translations may have been made in the other mode.
+	defaultCache.reset()
+}
+
+// translateMaterializedViews translates the materialized view statements:
+//
+//	CREATE MATERIALIZED VIEW [IF NOT EXISTS] v [(cols)] AS query [WITH [NO] DATA]
+//	  -> CREATE VIEW [IF NOT EXISTS] v [(cols)] AS query
+//	  -> CREATE TABLE [IF NOT EXISTS] v AS query          (snapshots)
+//	REFRESH MATERIALIZED VIEW [CONCURRENTLY] v [WITH [NO] DATA]
+//	  -> SELECT NULL WHERE 0                              (plain view)
+//	  -> DELETE FROM v; INSERT INTO v query               (snapshot)
+//	DROP MATERIALIZED VIEW [IF EXISTS] v
+//	  -> DROP VIEW [IF EXISTS] v / DROP TABLE [IF EXISTS] v
+//
+// In snapshot mode a column list is applied by selecting from a CTE with those
+// column names, and WITH NO DATA creates or leaves the table empty. The view's
+// query is recorded in the catalog by CREATE and removed by DROP. REFRESH or
+// DROP of a relation the catalog does not know as a materialized view fails as
+// in PG: 42P01 if it does not exist, 42809 if it is something else.
+func translateMaterializedViews(tokens []Token, sc *scope) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword {
+		return tokens
+	}
+	switch tokens[i].Value {
+	case "CREATE", "REFRESH", "DROP":
+	default:
+		return tokens
+	}
+	m := skipTrivia(tokens, i+1)
+	if m >= len(tokens) || tokens[m].Kind != TokKeyword || tokens[m].Value != "MATERIALIZED" {
+		return tokens
+	}
+	v := skipTrivia(tokens, m+1)
+	if v >= len(tokens) || tokens[v].Kind != TokKeyword || tokens[v].Value != "VIEW" {
+		return tokens
+	}
+
+	switch tokens[i].Value {
+	case "CREATE":
+		return translateCreateMatView(tokens, i, v, sc)
+	case "REFRESH":
+		return translateRefreshMatView(tokens, v, sc)
+	default:
+		return translateDropMatView(tokens, v, sc)
+	}
+}
+
+// matViewName reads the (possibly schema-qualified) view name starting at
+// tokens[start], returning its text and the index after it.
+func matViewName(tokens []Token, start int) (string, int) {
+	j := skipTrivia(tokens, start)
+	var name strings.Builder
+	for j < len(tokens) && (tokens[j].Kind == TokIdent || tokens[j].Kind == TokKeyword || tokens[j].Kind == TokDot) {
+		if tokens[j].Kind == TokKeyword && (tokens[j].Value == "AS" || tokens[j].Value == "WITH") {
+			break
+		}
+		name.WriteString(tokens[j].Raw)
+		j++
+	}
+	return name.String(), j
+}
+
+// matViewDataClause finds a trailing WITH [NO] DATA clause, returning where it
+// starts (len(tokens) when there is none) and whether it is WITH NO DATA.
+func matViewDataClause(tokens []Token) (int, bool) {
+	end := skipTriviaBack(tokens, len(tokens))
+	if end == 0 || !strings.EqualFold(tokens[end-1].Value, "DATA") {
+		return len(tokens), false
+	}
+	k := skipTriviaBack(tokens, end-1)
+	noData := false
+	if k > 0 && tokens[k-1].Kind == TokKeyword && tokens[k-1].Value == "NO" {
+		noData = true
+		k = skipTriviaBack(tokens, k-1)
+	}
+	if k == 0 || tokens[k-1].Kind != TokKeyword || tokens[k-1].Value != "WITH" {
+		return len(tokens), false
+	}
+	return skipTriviaBack(tokens, k-1), noData
+}
+
+// notMatView returns a statement failing as PG does when a materialized view
+// statement names relation name, which is not a materialized view: with 42P01
+// if there is no such relation, unless ifExists, and with 42809 if there is.
+func notMatView(name string, ifExists bool) string {
+	label := "'" + strings.ReplaceAll(strings.ReplaceAll(name, `"`, ""), "'", "''") + "'"
+	if ifExists {
+		rel := label
+		if dot := strings.LastIndexByte(label, '.'); dot >= 0 {
+			rel = "'" + label[dot+1:]
+		}
+		return "SELECT pg_not_matview(" + label + ") FROM sqlite_master WHERE type IN ('table', 'view') AND name = " + rel + " COLLATE NOCASE"
+	}
+	// SQLite reports a missing table when preparing the subquery.
+	return "SELECT pg_not_matview(" + label + ") WHERE NOT EXISTS (SELECT 1 FROM " + name + " WHERE 0)"
+}
+
+func translateCreateMatView(tokens []Token, create, view int, sc *scope) []Token {
+	ifNotExists := ""
+	j := view + 1
+	if k, ok := peekKeyword(tokens, j, "IF"); ok {
+		if k, ok = peekKeyword(tokens, k+1, "NOT"); ok {
+			if k, ok = peekKeyword(tokens, k+1, "EXISTS"); ok {
+				ifNotExists, j = " IF NOT EXISTS", k+1
+			}
+		}
+	}
+	name, j := matViewName(tokens, j)
+	if name == "" {
+		return tokens
+	}
+	var cols []Token
+	if k := skipTrivia(tokens, j); k < len(tokens) && tokens[k].Kind == TokParen && tokens[k].Value == "(" {
+		end := skipParenGroup(tokens, k)
+		cols, j = tokens[k:end+1], end+1
+	}
+	as, ok := peekKeyword(tokens, j, "AS")
+	if !ok {
+		return tokens
+	}
+	dataEnd, noData := matViewDataClause(tokens)
+	query := strings.TrimSpace(Reassemble(tokens[as+1 : dataEnd]))
+
+	matViews.mu.RLock()
+	snapshots := matViews.snapshots
+	matViews.mu.RUnlock()
+	key := strings.ToLower(name)
+	_, exists := sc.cat.matViews[key]
+	if !snapshots {
+		if ifNotExists == "" || !exists {
+			sc.change(catalogChange{kind: catalogMatView, name: key, value: query})
+		}
+		// Drop MATERIALIZED and the data clause; the rest is a valid view.
+		out := append([]Token(nil), tokens[:create+1]...)
+		out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+		out = append(out, tokens[view:dataEnd]...)
+		return out
+	}
+
+	if cols != nil {
+		query = "WITH _pglike_mv" + Reassemble(cols) + " AS (" + query + ") SELECT * FROM _pglike_mv"
+	}
+	if ifNotExists == "" || !exists {
+		sc.change(catalogChange{kind: catalogMatViewSnapshot, name: key, value: query})
+	}
+	if noData {
+		query = "SELECT * FROM (" + query + ") WHERE 0"
+	}
+	return Tokenize("CREATE TABLE" + ifNotExists + " " + name + " AS " + query)
+}
+
+func translateRefreshMatView(tokens []Token, view int, sc *scope) []Token {
+	j := view + 1
+	if k := skipTrivia(tokens, j); k < len(tokens) && strings.EqualFold(tokens[k].Value, "CONCURRENTLY") {
+		j = k + 1
+	}
+	name, _ := matViewName(tokens, j)
+	if name == "" {
+		return tokens
+	}
+	_, noData := matViewDataClause(tokens)
+
+	mv, ok := sc.cat.matViews[strings.ToLower(name)]
+	switch {
+	case !ok:
+		return Tokenize(notMatView(name, false))
+	case !mv.snapshot:
+		// A plain view is always current.
+		return Tokenize("SELECT NULL WHERE 0")
+	case noData:
+		return Tokenize("DELETE FROM " + name)
+	default:
+		return Tokenize("DELETE FROM " + name + "; INSERT INTO " + name + " " + mv.query)
+	}
+}
+
+func translateDropMatView(tokens []Token, view int, sc *scope) []Token {
+	ifExists := ""
+	j := view + 1
+	if k, ok := peekKeyword(tokens, j, "IF"); ok {
+		if k, ok = peekKeyword(tokens, k+1, "EXISTS"); ok {
+			ifExists, j = " IF EXISTS", k+1
+		}
+	}
+	name, _ := matViewName(tokens, j)
+	if name == "" {
+		return tokens
+	}
+
+	key := strings.ToLower(name)
+	mv, ok := sc.cat.matViews[key]
+	if !ok {
+		return Tokenize(notMatView(name, ifExists != ""))
+	}
+	if !mv.snapshot {
+		sc.change(catalogChange{kind: catalogMatView, name: key, remove: true})
+		return Tokenize("DROP VIEW" + ifExists + " " + name)
+	}
+	sc.change(catalogChange{kind: catalogMatViewSnapshot, name: key, remove: true})
+	return Tokenize("DROP TABLE" + ifExists + " " + name)
+}
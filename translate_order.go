@@ -1,5 +1,10 @@
 package pglike
 
+import (
+	"strconv"
+	"strings"
+)
+
 // translateNullsOrdering rewrites NULLS FIRST / NULLS LAST in ORDER BY clauses.
 // "ORDER BY col [ASC|DESC] NULLS FIRST" ->
 // "ORDER BY (CASE WHEN col IS NULL THEN 0 ELSE 1 END), col [ASC|DESC]"
@@ -153,3 +158,108 @@ func findColumnExprStart(tokens []Token, pos int) int {
 
 	return pos
 }
+
+// translateEnumOrdering makes ORDER BY on enum columns follow declaration order,
+// as PG does, instead of sorting the stored TEXT alphabetically:
+//
+//	ORDER BY status DESC -> ORDER BY CASE status WHEN 'sad' THEN 0 WHEN 'ok' THEN 1 ... END DESC
+//
+// Only plain (optionally table-qualified) references to columns the catalog
+// records with an enum type are rewritten; expressions over them sort as text.
+func translateEnumOrdering(tokens []Token, sc *scope) []Token {
+	var out []Token
+	var orders []int // paren depths of the ORDER BY clauses being scanned
+	depth := 0
+	itemStart := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if itemStart && t.Kind != TokWhitespace {
+			itemStart = false
+			if expr, end, ok := enumOrderExpr(tokens, i, sc); ok {
+				out = append(out, expr...)
+				i = end
+				continue
+			}
+		}
+		inOrder := len(orders) > 0 && orders[len(orders)-1] == depth
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			if inOrder {
+				orders = orders[:len(orders)-1]
+			}
+			depth--
+		case t.Kind == TokKeyword && t.Value == "BY" && len(out) > 0 && prevKeyword(out) == "ORDER":
+			orders = append(orders, depth)
+			itemStart = true
+		case inOrder && t.Kind == TokComma:
+			itemStart = true
+		case inOrder && (t.Kind == TokSemicolon || (t.Kind == TokKeyword && orderClauseEnd[t.Value])):
+			orders = orders[:len(orders)-1]
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// orderClauseEnd lists keywords that end an ORDER BY list.
+var orderClauseEnd = map[string]bool{
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+}
+
+// prevKeyword returns the value of the last non-whitespace token if it is a keyword.
+func prevKeyword(out []Token) string {
+	j := len(out) - 1
+	for j >= 0 && out[j].Kind == TokWhitespace {
+		j--
+	}
+	if j < 0 || out[j].Kind != TokKeyword {
+		return ""
+	}
+	return out[j].Value
+}
+
+// enumOrderExpr matches a sort item at i that is just an enum column reference
+// ([table.]col, followed by ASC/DESC/NULLS, a comma or the end of the list) and
+// returns the CASE expression mapping its values to their declaration index.
+func enumOrderExpr(tokens []Token, i int, sc *scope) ([]Token, int, bool) {
+	end := i
+	if end+2 < len(tokens) && tokens[end].Kind == TokIdent && tokens[end+1].Kind == TokDot {
+		end += 2
+	}
+	if end >= len(tokens) || tokens[end].Kind != TokIdent {
+		return nil, 0, false
+	}
+	if k := end + 1; k < len(tokens) {
+		for k < len(tokens) && tokens[k].Kind == TokWhitespace {
+			k++
+		}
+		if k < len(tokens) {
+			next := tokens[k]
+			switch {
+			case next.Kind == TokComma, next.Kind == TokSemicolon, next.Kind == TokParen && next.Value == ")":
+			case next.Kind == TokKeyword && (next.Value == "ASC" || next.Value == "DESC" || next.Value == "NULLS" || orderClauseEnd[next.Value]):
+			default:
+				return nil, 0, false
+			}
+		}
+	}
+	typ, ok := sc.refType(tokens[i : end+1])
+	if !ok {
+		return nil, 0, false
+	}
+	values, ok := sc.enum(typ)
+	if !ok {
+		return nil, 0, false
+	}
+
+	var b strings.Builder
+	b.WriteString("CASE ")
+	b.WriteString(Reassemble(tokens[i : end+1]))
+	for idx, v := range values {
+		b.WriteString(" WHEN '" + strings.ReplaceAll(v, "'", "''") + "' THEN " + strconv.Itoa(idx))
+	}
+	b.WriteString(" END")
+	return Tokenize(b.String()), end, true
+}
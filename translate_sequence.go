@@ -1,6 +1,10 @@
 package pglike
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // translateSequenceDDL translates CREATE SEQUENCE and DROP SEQUENCE statements.
 // CREATE SEQUENCE name [INCREMENT BY n] [START WITH n] ->
@@ -149,3 +153,406 @@ func translateDropSequence(tokens []Token, start int) ([]Token, bool) {
 	sql := fmt.Sprintf("DELETE FROM _sequences WHERE name = '%s'", seqName)
 	return Tokenize(sql), true
 }
+
+// seqDefault is a column declared DEFAULT nextval('seq').
+type seqDefault struct {
+	col string // column name
+	seq string // sequence name
+}
+
+// insertDefault is a column default that the translator, rather than SQLite,
+// puts in INSERTs: that of a column declared DEFAULT nextval('seq'), or added
+// by ALTER TABLE ... ADD COLUMN with a default SQLite can't add.
+type insertDefault struct {
+	col   string // column name
+	value string // the column's value, in SQLite's dialect
+}
+
+// tableInsertDefaults returns the columns of table whose defaults the catalog
+// records for INSERTs to supply, ordered by column name.
+func tableInsertDefaults(cat *catalog, table string) []insertDefault {
+	var defaults []insertDefault
+	for _, col := range tableKeys(cat.seqDefaults, table) {
+		seq := cat.seqDefaults[table+"."+col]
+		defaults = append(defaults, insertDefault{col: col, value: "pg_nextval('" + strings.ReplaceAll(seq, "'", "''") + "')"})
+	}
+	for _, col := range tableKeys(cat.addedDefaults, table) {
+		defaults = append(defaults, insertDefault{col: col, value: cat.addedDefaults[table+"."+col]})
+	}
+	slices.SortFunc(defaults, func(a, b insertDefault) int { return strings.Compare(a.col, b.col) })
+	return defaults
+}
+
+// translateSequenceDefaults handles columns declared DEFAULT nextval('seq') in
+// CREATE TABLE. SQLite only accepts constant defaults, so the clause is
+// dropped from the table and the column is recorded in the catalog:
+//
+//	CREATE TABLE t (id BIGINT NOT NULL DEFAULT nextval('t_seq'), n TEXT)
+//	  -> CREATE TABLE t (id INTEGER NOT NULL, n TEXT)
+//
+// INSERTs that leave the column out, or set it to DEFAULT, get pg_nextval('seq')
+// in its place, which draws a value for each row as SQLite inserts it, so that
+// RETURNING and currval see the value (see registerNextval). An explicit NULL
+// is kept, and rejected by NOT NULL, as in PG:
+//
+//	INSERT INTO t (n) VALUES ('a'), ('b') -> INSERT INTO t (n, id) VALUES ('a', pg_nextval('t_seq')), ('b', pg_nextval('t_seq'))
+//	INSERT INTO t (id, n) VALUES (DEFAULT, 'a') -> INSERT INTO t (id, n) VALUES (pg_nextval('t_seq'), 'a')
+//	INSERT INTO t DEFAULT VALUES -> INSERT INTO t (id) VALUES (pg_nextval('t_seq'))
+//	INSERT INTO t (n) SELECT n FROM src -> INSERT INTO t (n, id) SELECT *, pg_nextval('t_seq') FROM (SELECT n FROM src)
+//
+// An INSERT without a column list is given the columns its values are for,
+// which the catalog records in order (see recordColumnTypes), and is then
+// handled the same way:
+//
+//	INSERT INTO t VALUES (DEFAULT, 'a') -> INSERT INTO t (id, n) VALUES (pg_nextval('t_seq'), 'a')
+//
+// That needs the table to have been created through pglike, and for INSERT ...
+// SELECT a select list without *; other INSERTs without a column list are left
+// as they are.
+//
+// Columns whose default ALTER TABLE ... ADD COLUMN added are filled in the same
+// way, with the default expression (see translateAddColumnDefault).
+func translateSequenceDefaults(tokens []Token, sc *scope) []Token {
+	i := skipTrivia(tokens, 0)
+	if i >= len(tokens) || tokens[i].Kind != TokKeyword {
+		return tokens
+	}
+	switch tokens[i].Value {
+	case "CREATE":
+		return recordSequenceDefaults(tokens, i, sc)
+	case "INSERT":
+		return insertColumnDefaults(tokens, sc)
+	}
+	return tokens
+}
+
+// tableConstraintWords start table constraints rather than column definitions.
+var tableConstraintWords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true, "FOREIGN": true, "EXCLUDE": true,
+}
+
+func recordSequenceDefaults(tokens []Token, create int, sc *scope) []Token {
+	// CREATE [TEMP|TEMPORARY|UNLOGGED] TABLE [IF NOT EXISTS] name (
+	j := skipTrivia(tokens, create+1)
+	for j < len(tokens) && tokens[j].Kind == TokKeyword && tokens[j].Value != "TABLE" {
+		j = skipTrivia(tokens, j+1)
+	}
+	if j >= len(tokens) || tokens[j].Kind != TokKeyword {
+		return tokens
+	}
+	table, ifNotExists := "", false
+	for j < len(tokens) && !(tokens[j].Kind == TokParen && tokens[j].Value == "(") {
+		if tokens[j].Kind == TokSemicolon || tokens[j].Kind == TokKeyword && tokens[j].Value == "AS" {
+			return tokens
+		}
+		if tokens[j].Kind == TokKeyword && tokens[j].Value == "EXISTS" {
+			ifNotExists = true
+		}
+		if tokens[j].Kind == TokIdent {
+			table = identName(tokens[j])
+		}
+		j++
+	}
+	if j >= len(tokens) || table == "" {
+		return tokens
+	}
+
+	var defaults []seqDefault
+	var col Token
+	isColumn := false
+	out := append([]Token(nil), tokens[:j+1]...)
+	depth := 1
+	defStart := true
+	for k := j + 1; k < len(tokens); k++ {
+		t := tokens[k]
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		}
+		if depth == 0 {
+			out = append(out, tokens[k:]...)
+			break
+		}
+		if depth == 1 && t.Kind == TokComma {
+			defStart = true
+			out = append(out, t)
+			continue
+		}
+		if depth == 1 && defStart && t.Kind != TokWhitespace && t.Kind != TokComment {
+			defStart = false
+			col = t
+			isColumn = t.Kind == TokIdent || t.Kind == TokKeyword && !tableConstraintWords[t.Value]
+		}
+		if depth == 1 && isColumn && t.Kind == TokKeyword && t.Value == "DEFAULT" {
+			if lit, end, ok := nextvalCall(tokens, skipTrivia(tokens, k+1)); ok {
+				seq := strings.ReplaceAll(strings.Trim(lit.Value, "'"), "''", "'")
+				defaults = append(defaults, seqDefault{col: identName(col), seq: seq})
+				for len(out) > 0 && out[len(out)-1].Kind == TokWhitespace {
+					out = out[:len(out)-1]
+				}
+				k = end
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+
+	// CREATE TABLE IF NOT EXISTS leaves an existing table as it is.
+	if !ifNotExists || len(sc.cat.tableColumns(table))+len(tableKeys(sc.cat.seqDefaults, table)) == 0 {
+		for _, c := range tableKeys(sc.cat.seqDefaults, table) {
+			sc.change(catalogChange{kind: catalogSeqDefault, name: table + "." + c, remove: true})
+		}
+		for _, d := range defaults {
+			sc.change(catalogChange{kind: catalogSeqDefault, name: table + "." + d.col, value: d.seq})
+		}
+	}
+	if len(defaults) == 0 {
+		return tokens
+	}
+	return out
+}
+
+// nextvalCall matches nextval('seq') or nextval('seq'::regclass) at
+// tokens[i], returning the sequence literal and the index of the close paren.
+func nextvalCall(tokens []Token, i int) (Token, int, bool) {
+	if i >= len(tokens) || tokens[i].Kind != TokIdent || !strings.EqualFold(tokens[i].Value, "nextval") || !isFuncCall(tokens, i) {
+		return Token{}, 0, false
+	}
+	args, end := parseFuncArgs(tokens, skipTrivia(tokens, i+1))
+	if len(args) != 1 {
+		return Token{}, 0, false
+	}
+	arg := trimTokenWhitespace(args[0])
+	if len(arg) == 0 || arg[0].Kind != TokString {
+		return Token{}, 0, false
+	}
+	if len(arg) > 1 && !(len(arg) == 3 && arg[1].Value == "::" && strings.EqualFold(arg[2].Value, "regclass")) {
+		return Token{}, 0, false
+	}
+	return arg[0], end, true
+}
+
+func insertColumnDefaults(tokens []Token, sc *scope) []Token {
+	table, j, ok := insertTarget(tokens)
+	if !ok {
+		return tokens
+	}
+	defaults := tableInsertDefaults(sc.cat, table)
+	if len(defaults) == 0 {
+		return tokens
+	}
+	call := func(d insertDefault) []Token {
+		return Tokenize(d.value)
+	}
+	name := func(d insertDefault) []Token {
+		return []Token{{Kind: TokIdent, Value: d.col, Raw: quoteIdent(d.col)}}
+	}
+	comma := []Token{{Kind: TokComma, Value: ",", Raw: ","}, {Kind: TokWhitespace, Value: " ", Raw: " "}}
+
+	// INSERT INTO t DEFAULT VALUES
+	if d, ok := peekKeyword(tokens, j, "DEFAULT"); ok {
+		if v, ok := peekKeyword(tokens, d+1, "VALUES"); ok {
+			out := append([]Token(nil), tokens[:j]...)
+			var cols, vals []Token
+			for n, def := range defaults {
+				if n > 0 {
+					cols, vals = append(cols, comma...), append(vals, comma...)
+				}
+				cols, vals = append(cols, name(def)...), append(vals, call(def)...)
+			}
+			out = append(out, Tokenize(" (")...)
+			out = append(out, cols...)
+			out = append(out, Tokenize(") VALUES (")...)
+			out = append(out, vals...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+			return append(out, tokens[v+1:]...)
+		}
+	}
+
+	tokens = insertColumnList(tokens, j, table, sc)
+
+	// INSERT INTO t (cols) SELECT ...
+	if open := skipTrivia(tokens, j); open < len(tokens) && tokens[open].Kind == TokParen && tokens[open].Value == "(" {
+		cols, closeCols := parseFuncArgs(tokens, open)
+		if sel := skipTrivia(tokens, closeCols+1); sel < len(tokens) && tokens[sel].Kind == TokKeyword && (tokens[sel].Value == "SELECT" || tokens[sel].Value == "WITH") {
+			var extra []insertDefault
+			for _, def := range defaults {
+				if !slices.ContainsFunc(cols, func(col []Token) bool {
+					col = trimTokenWhitespace(col)
+					return len(col) == 1 && identName(col[0]) == def.col
+				}) {
+					extra = append(extra, def)
+				}
+			}
+			if len(extra) == 0 {
+				return tokens
+			}
+			end := insertSelectEnd(tokens, sel)
+			out := append([]Token(nil), tokens[:closeCols]...)
+			for _, def := range extra {
+				out = append(append(out, comma...), name(def)...)
+			}
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+			out = append(out, tokens[closeCols+1:sel]...)
+			out = append(out, Tokenize("SELECT *")...)
+			for _, def := range extra {
+				out = append(append(out, comma...), call(def)...)
+			}
+			out = append(out, Tokenize(" FROM (")...)
+			out = append(out, trimTokenWhitespace(tokens[sel:end])...)
+			out = append(out, Token{Kind: TokParen, Value: ")", Raw: ")"})
+			if end < len(tokens) && tokens[end].Value == "ON" {
+				// Without a WHERE clause, SQLite would read ON CONFLICT as a
+				// join constraint.
+				out = append(out, Tokenize(" WHERE 1")...)
+			}
+			if end < len(tokens) {
+				out = append(out, Token{Kind: TokWhitespace, Value: " ", Raw: " "})
+			}
+			return append(out, tokens[end:]...)
+		}
+	}
+
+	open, cols, rows, end, ok := parseInsertValues(tokens)
+	if !ok {
+		return tokens
+	}
+	var extra []insertDefault
+	for _, def := range defaults {
+		found := false
+		for c, col := range cols {
+			col = trimTokenWhitespace(col)
+			if len(col) != 1 || identName(col[0]) != def.col {
+				continue
+			}
+			found = true
+			for _, row := range rows {
+				v := trimTokenWhitespace(row[c])
+				if len(v) == 1 && v[0].Kind == TokKeyword && v[0].Value == "DEFAULT" {
+					row[c] = call(def)
+				}
+			}
+		}
+		if !found {
+			extra = append(extra, def)
+		}
+	}
+
+	list := func(items [][]Token, add func(insertDefault) []Token) []Token {
+		l := []Token{{Kind: TokParen, Value: "(", Raw: "("}}
+		for n, item := range items {
+			if n > 0 {
+				l = append(l, comma...)
+			}
+			l = append(l, trimTokenWhitespace(item)...)
+		}
+		for _, def := range extra {
+			l = append(l, comma...)
+			l = append(l, add(def)...)
+		}
+		return append(l, Token{Kind: TokParen, Value: ")", Raw: ")"})
+	}
+	out := append([]Token(nil), tokens[:open]...)
+	out = append(out, list(cols, name)...)
+	out = append(out, Tokenize(" VALUES ")...)
+	for r, row := range rows {
+		if r > 0 {
+			out = append(out, comma...)
+		}
+		out = append(out, list(row, call)...)
+	}
+	return append(out, tokens[end+1:]...)
+}
+
+// insertColumnList gives INSERT INTO table VALUES ... or INSERT INTO table
+// SELECT ..., whose table name ends before tokens[j], the column list PG
+// implies: the table's first columns, as many as there are values. The
+// catalog must know the table's columns, and a select list must not use *;
+// otherwise tokens are returned unchanged.
+func insertColumnList(tokens []Token, j int, table string, sc *scope) []Token {
+	names, ok := sc.cat.columnLists[table]
+	k := skipTrivia(tokens, j)
+	if !ok || k >= len(tokens) || tokens[k].Kind != TokKeyword {
+		return tokens
+	}
+	width := 0
+	switch tokens[k].Value {
+	case "VALUES":
+		if p := skipTrivia(tokens, k+1); p < len(tokens) && tokens[p].Kind == TokParen && tokens[p].Value == "(" {
+			row, _ := parseFuncArgs(tokens, p)
+			width = len(row)
+		}
+	case "SELECT":
+		width = selectListWidth(tokens, k)
+	}
+	if width == 0 || width > len(names) {
+		return tokens
+	}
+	quoted := make([]string, width)
+	for n, col := range names[:width] {
+		quoted[n] = quoteIdent(col)
+	}
+	out := append([]Token(nil), tokens[:j]...)
+	out = append(out, Tokenize(" ("+strings.Join(quoted, ", ")+")")...)
+	return append(out, tokens[j:]...)
+}
+
+// selectListWidth returns the number of items in the select list of the
+// SELECT at tokens[sel], or 0 when it has a * item.
+func selectListWidth(tokens []Token, sel int) int {
+	end, depth := len(tokens), 0
+	for k := sel + 1; k < len(tokens); k++ {
+		t := tokens[k]
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth == 0 && (t.Kind == TokSemicolon || t.Kind == TokKeyword && selectListEnd[t.Value]):
+			end = k
+		}
+		if end < len(tokens) {
+			break
+		}
+	}
+	items := splitTopLevel(tokens[sel+1 : end])
+	for _, item := range items {
+		item = trimTokenWhitespace(item)
+		if len(item) == 0 || item[len(item)-1].Kind == TokOperator && item[len(item)-1].Value == "*" {
+			return 0
+		}
+	}
+	return len(items)
+}
+
+// selectListEnd are the keywords that can follow a select list.
+var selectListEnd = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true, "LIMIT": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "RETURNING": true, "ON": true,
+}
+
+// insertSelectEnd returns the index just past the query of INSERT ... SELECT,
+// which starts at tokens[sel]: the first top-level ON CONFLICT, RETURNING or
+// semicolon, or the end of tokens.
+func insertSelectEnd(tokens []Token, sel int) int {
+	depth := 0
+	for k := sel; k < len(tokens); k++ {
+		t := tokens[k]
+		switch {
+		case t.Kind == TokParen && t.Value == "(":
+			depth++
+		case t.Kind == TokParen && t.Value == ")":
+			depth--
+		case depth != 0:
+		case t.Kind == TokSemicolon, t.Kind == TokKeyword && t.Value == "RETURNING":
+			return k
+		case t.Kind == TokKeyword && t.Value == "ON":
+			if _, ok := peekKeyword(tokens, k+1, "CONFLICT"); ok {
+				return k
+			}
+		}
+	}
+	return len(tokens)
+}
@@ -0,0 +1,76 @@
+package pglike
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// strictMode makes Translate reject constructs it knows SQLite cannot run.
+var strictMode atomic.Bool
+
+// SetTranslateStrict selects whether translation fails on PostgreSQL syntax
+// that has no SQLite equivalent: DISTINCT ON, GROUPING SETS, ROLLUP, CUBE,
+// WITH ORDINALITY and MERGE, and TABLESAMPLE and LATERAL. By default such SQL
+// is passed through and SQLite reports a syntax error, except that TABLESAMPLE
+// and LATERAL are stripped; in strict mode the error is a PGError with code
+// 0A000 naming the construct. The setting is process-wide.
+func SetTranslateStrict(enabled bool) {
+	strictMode.Store(enabled)
+	// Cached translations were made without the check.
+	defaultCache.reset()
+}
+
+// checkStrict returns an error naming the first unsupported construct in
+// tokens when strict mode is on.
+func checkStrict(tokens []Token) error {
+	if !strictMode.Load() {
+		return nil
+	}
+	if construct := unsupportedConstruct(tokens); construct != "" {
+		return &PGError{
+			Code:    "0A000", // feature_not_supported
+			Message: construct + " is not supported",
+		}
+	}
+	return nil
+}
+
+// unsupportedConstruct returns the name of the first construct in tokens that
+// SQLite cannot run, or "" if there is none.
+func unsupportedConstruct(tokens []Token) string {
+	for i, t := range tokens {
+		if t.Kind != TokKeyword && t.Kind != TokIdent || strings.HasPrefix(t.Raw, `"`) {
+			continue
+		}
+		word := strings.ToUpper(t.Value)
+		next := skipTrivia(tokens, i+1)
+		nextIs := func(v string) bool {
+			return next < len(tokens) && strings.EqualFold(tokens[next].Value, v)
+		}
+		switch word {
+		case "TABLESAMPLE", "LATERAL":
+			return word
+		case "DISTINCT":
+			if nextIs("ON") {
+				return "DISTINCT ON"
+			}
+		case "GROUPING":
+			if nextIs("SETS") {
+				return "GROUPING SETS"
+			}
+		case "ROLLUP", "CUBE":
+			if p := prevSignificant(tokens, i); p >= 0 && tokens[p].Value == "BY" && nextIs("(") {
+				return word
+			}
+		case "WITH":
+			if nextIs("ORDINALITY") {
+				return "WITH ORDINALITY"
+			}
+		case "MERGE":
+			if skipTrivia(tokens, 0) == i && nextIs("INTO") {
+				return "MERGE"
+			}
+		}
+	}
+	return ""
+}